@@ -6,9 +6,8 @@ import (
 	"path/filepath"
 	"strings"
 
-	"github.com/malashin/ffinfo"
-
 	"github.com/macroblock/imed/pkg/cli"
+	"github.com/macroblock/imed/pkg/ffcache"
 	"github.com/macroblock/imed/pkg/misc"
 	"github.com/macroblock/imed/pkg/tagname"
 	"github.com/macroblock/imed/pkg/zlog/loglevel"
@@ -118,7 +117,7 @@ func doProcess(filePath string, isDeepCheck bool) string {
 	newPath, err := tn.ConvertTo("")
 	retif.Error(err, "cannot convert to '"+tn.Schema()+"' schema")
 
-	file, err := ffinfo.Probe(filePath)
+	file, err := ffcache.Probe(filePath)
 	retif.Error(err, "ffinfo.Probe() (ffprobe)")
 
 	sar := ""
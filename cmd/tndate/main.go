@@ -5,6 +5,7 @@ import (
 	"os"
 	"time"
 
+	"github.com/macroblock/imed/pkg/journal"
 	"github.com/macroblock/imed/pkg/misc"
 	"github.com/macroblock/imed/pkg/tagname"
 	"github.com/macroblock/imed/pkg/zlog/loglevel"
@@ -15,6 +16,8 @@ var (
 	log       = zlog.Instance("main")
 	retif     = log.Catcher()
 	logFilter = loglevel.Warning.OrLower()
+
+	renameJournal *journal.TJournal
 )
 
 func doProcess(path string, schema string, isDeepCheck bool) {
@@ -38,7 +41,11 @@ func doProcess(path string, schema string, isDeepCheck bool) {
 	newPath, err := tn.ConvertTo(schema)
 	retif.Error(err, "cannot convert to '"+schema+"'")
 
-	err = os.Rename(path, newPath)
+	journalSchema := schema
+	if journalSchema == "" {
+		journalSchema = tn.Schema()
+	}
+	err = renameJournal.Rename(path, newPath, journalSchema)
 	retif.Error(err, "cannot rename file")
 
 	log.Notice(schema, " > ", newPath)
@@ -64,11 +71,29 @@ func main() {
 	// process command line arguments
 	if len(os.Args) <= 1 {
 		log.Warning(true, "not enough parameters")
-		log.Info("Usage:\n    tnrename [-rt|-old] {filename}\n")
+		log.Info("Usage:\n    tndate [-rt|-old] {filename}\n    tndate --undo <journal>\n\n$" + tagname.EnvVocabularyFile + " adds words of the vocabulary file to the grammar\n")
+		return
+	}
+
+	if os.Args[1] == "--undo" {
+		if len(os.Args) != 3 {
+			log.Warning(true, "--undo requires a journal file")
+			return
+		}
+		err := journal.Undo(os.Args[2], func(e *journal.TEntry) {
+			log.Notice("undo > ", e.Source)
+		})
+		log.Error(err)
 		return
 	}
 
 	// main job
+	if path := os.Getenv(tagname.EnvVocabularyFile); path != "" {
+		if err := tagname.LoadVocabularyFile(path); err != nil {
+			log.Error(err)
+			return
+		}
+	}
 	args := os.Args[1:]
 	schema := ""
 	switch args[0] {
@@ -80,8 +105,20 @@ func main() {
 		args = args[1:]
 	}
 
+	journalPath, err := journal.DefaultPath("tndate")
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	renameJournal = journal.Open(journalPath, "tndate")
+
 	// wasError := false
 	for _, path := range args {
 		doProcess(path, schema, false) //tagname.CheckDeepStrict)
 	}
+
+	if !renameJournal.IsEmpty() {
+		log.Notice("journal: ", renameJournal.Path())
+	}
+	log.Error(renameJournal.Close())
 }
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/macroblock/imed/pkg/cli"
+	"github.com/macroblock/imed/pkg/journal"
+	"github.com/macroblock/imed/pkg/misc"
+	"github.com/macroblock/imed/pkg/renplan"
+	"github.com/macroblock/imed/pkg/tagname"
+	"github.com/macroblock/imed/pkg/zlog/loglevel"
+	"github.com/macroblock/imed/pkg/zlog/zlog"
+)
+
+var (
+	log = zlog.Instance("main")
+
+	flagFrom          = "old"
+	flagTo            = "rt"
+	flagDoRename      bool
+	flagSkipLossy     bool
+	flagPlanFile      string
+	flagFailuresFile  string
+	flagFileList      string
+	flagSchemaFiles   []string
+	flagVocabFile     = os.Getenv(tagname.EnvVocabularyFile)
+	flagJournal       string
+	flagUndo          string
+	flagResolveCycles bool
+	flagDontPause     bool
+	flagFiles         []string
+)
+
+// tItem - a file of the migration
+type tItem struct {
+	path      string
+	migration *tagname.TMigration
+	skip      string // a reason to leave the file as it is
+	failure   string // a reason the file cannot be migrated
+}
+
+// collectPaths - files of the arguments, directories are walked recursively
+func collectPaths(args []string) ([]string, error) {
+	ret := []string{}
+	for _, arg := range args {
+		fi, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !fi.IsDir() {
+			ret = append(ret, arg)
+			continue
+		}
+		err = filepath.Walk(arg, func(path string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if path != arg && strings.HasPrefix(fi.Name(), ".") {
+				if fi.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if fi.Mode().IsRegular() {
+				ret = append(ret, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+// prepare - converts the file and verifies the round trip
+func prepare(path string) *tItem {
+	item := &tItem{path: path}
+	tn, err := tagname.NewFromFilename(path, false, flagFrom)
+	if tn == nil {
+		if _, e := tagname.NewFromFilename(path, false, flagTo); e == nil {
+			item.skip = "already '" + flagTo + "'"
+			return item
+		}
+		item.failure = fmt.Sprintf("does not parse under '%v': %v", flagFrom, err)
+		return item
+	}
+	if err != nil {
+		item.failure = fmt.Sprintf("check: %v", err)
+		return item
+	}
+	m := tagname.Migrate(tn, flagTo)
+	item.migration = m
+	switch {
+	case m.Err != nil:
+		item.failure = m.Err.Error()
+	case !m.IsRoundTrip():
+		item.failure = fmt.Sprintf("tags do not survive the round trip: %v", strings.Join(m.Changed, " "))
+	case !m.IsLossless() && flagSkipLossy:
+		item.skip = "lossy"
+	case m.Target == m.Source:
+		item.skip = "unchanged"
+	}
+	return item
+}
+
+// writePlan - writes the migration plan and returns the number of failures
+func writePlan(w io.Writer, items []*tItem, plan *renplan.TPlan) int {
+	fmt.Fprintf(w, "migration plan: '%v' -> '%v'\n", flagFrom, flagTo)
+	nRename, nLossy, nSkip := 0, 0, 0
+	failures := []*tItem{}
+	for _, item := range items {
+		switch {
+		case item.failure != "":
+			failures = append(failures, item)
+			continue
+		case item.skip != "":
+			nSkip++
+			fmt.Fprintf(w, "  skip   %v (%v)\n", item.path, item.skip)
+			continue
+		}
+		m := item.migration
+		nRename++
+		if m.IsLossless() {
+			fmt.Fprintf(w, "  rename %v\n      -> %v\n", m.Source, m.Target)
+			continue
+		}
+		nLossy++
+		fmt.Fprintf(w, "  lossy  %v\n      -> %v\n      back to '%v': %v\n", m.Source, m.Target, flagFrom, m.Back)
+		for _, s := range m.Lost {
+			fmt.Fprintf(w, "      lost %v\n", s)
+		}
+	}
+	conflicts := plan.Errors(flagResolveCycles)
+	if len(conflicts) > 0 {
+		fmt.Fprintf(w, "conflicts (%v):\n", len(conflicts))
+		for _, c := range conflicts {
+			fmt.Fprintf(w, "  %v\n", c.Message)
+		}
+	}
+	if len(failures) > 0 {
+		fmt.Fprintf(w, "failures (%v):\n", len(failures))
+		for _, item := range failures {
+			fmt.Fprintf(w, "  %v\n      %v\n", item.path, strings.ReplaceAll(item.failure, "\n", "\n      "))
+		}
+	}
+	fmt.Fprintf(w, "total %v: %v to rename (%v lossy), %v skipped, %v failed, %v conflicts\n",
+		len(items), nRename, nLossy, nSkip, len(failures), len(conflicts))
+	return len(failures)
+}
+
+// writeFailures - writes paths of the failed files one per line
+func writeFailures(path string, items []*tItem) error {
+	b := &strings.Builder{}
+	for _, item := range items {
+		if item.failure != "" {
+			fmt.Fprintln(b, item.path)
+		}
+	}
+	return os.WriteFile(path, []byte(b.String()), 0644)
+}
+
+func mainFunc() error {
+	if flagUndo != "" {
+		return journal.Undo(flagUndo, func(e *journal.TEntry) {
+			log.Notice("undo > ", e.Source)
+		})
+	}
+	if flagVocabFile != "" {
+		if err := tagname.LoadVocabularyFile(flagVocabFile); err != nil {
+			return err
+		}
+	}
+	for _, path := range flagSchemaFiles {
+		if _, err := tagname.LoadSchemaFile(path); err != nil {
+			return err
+		}
+	}
+	for _, name := range []string{flagFrom, flagTo} {
+		if _, err := tagname.Schema(name); err != nil {
+			return fmt.Errorf("unknown schema %q (registered: %v)", name, strings.Join(tagname.Schemas(), ", "))
+		}
+	}
+
+	args := append([]string(nil), flagFiles...)
+	if flagFileList != "" {
+		file, err := os.Open(flagFileList)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				args = append(args, line)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+	}
+	if len(args) == 0 {
+		return cli.ErrorNotEnoughArguments()
+	}
+	paths, err := collectPaths(args)
+	if err != nil {
+		return err
+	}
+
+	items := []*tItem{}
+	plan := renplan.New()
+	for _, path := range paths {
+		item := prepare(path)
+		if item.failure == "" && item.skip == "" {
+			plan.Add(item.migration.Source, item.migration.Target, flagTo)
+		}
+		items = append(items, item)
+	}
+
+	w := io.Writer(os.Stdout)
+	if flagPlanFile != "" {
+		f, err := os.Create(flagPlanFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = io.MultiWriter(os.Stdout, f)
+	}
+	failed := writePlan(w, items, plan)
+	if flagFailuresFile != "" {
+		if err := writeFailures(flagFailuresFile, items); err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		log.Warning(true, failed, " file(s) cannot be migrated")
+	}
+
+	if !flagDoRename {
+		log.Notice("nothing is renamed, use -n to apply the plan")
+		return nil
+	}
+	path := flagJournal
+	if path == "" {
+		if path, err = journal.DefaultPath("tnmigrate"); err != nil {
+			return err
+		}
+	}
+	renameJournal := journal.Open(path, "tnmigrate")
+	err = plan.Apply(flagResolveCycles, renameJournal.Rename)
+	if !renameJournal.IsEmpty() {
+		log.Notice("journal: ", renameJournal.Path())
+	}
+	log.Error(renameJournal.Close())
+	// not a usage error, so the cli hint is not printed
+	log.Error(err)
+	return nil
+}
+
+func main() {
+	// setup log
+	newLogger := misc.NewSimpleLogger
+	if misc.IsTerminal() {
+		newLogger = misc.NewAnsiLogger
+	}
+	log.Add(
+		newLogger(loglevel.Warning.OrLower(), ""),
+		newLogger(loglevel.Info.Only().Include(loglevel.Notice.Only()), "~x\n"),
+	)
+
+	defer func() {
+		if log.State().Intersect(loglevel.Warning.OrLower()) != 0 && !flagDontPause {
+			misc.PauseTerminal()
+		}
+	}()
+
+	// command line interface
+	cmdLine := cli.New("!PROG! the program that migrates tagged files to another schema.", mainFunc)
+	cmdLine.Elements(
+		cli.Usage("!PROG! {flags|<...>}"),
+		cli.Flag("-h --help   : help", cmdLine.PrintHelp).Terminator(),
+		cli.Flag("--from      : a schema of the files (default 'old')", &flagFrom),
+		cli.Flag("--to        : a schema to migrate to (default 'rt')", &flagTo),
+		cli.Flag("-S --schema-file : load a schema definition file (can be repeated)", &flagSchemaFiles),
+		cli.Flag("--vocabulary-file: add words of the vocabulary file to the grammar (default: $"+tagname.EnvVocabularyFile+")", &flagVocabFile),
+		cli.Flag("-n --do-rename: apply the plan", &flagDoRename),
+		cli.Flag("--skip-lossy: do not rename files that cannot be converted back without losses", &flagSkipLossy),
+		cli.Flag("-o --plan   : also write the plan report to the file", &flagPlanFile),
+		cli.Flag("--failures  : write paths of the files that cannot be migrated to the file", &flagFailuresFile),
+		cli.Flag("-J --journal: a journal file to record renames to (default: a new file in "+journal.EnvJournalDir+" or the user cache dir)", &flagJournal),
+		cli.Flag("-u --undo   : revert renames recorded in the journal file", &flagUndo),
+		cli.Flag("--resolve-cycles: apply renames that form a cycle (a->b, b->a) through temporary names", &flagResolveCycles),
+		cli.Flag("-k          : do not wait key press on errors", &flagDontPause),
+		cli.Flag("-l --filelist   : specifies a file that contains list of files to process", &flagFileList),
+		cli.Flag(": files or directories to be processed", &flagFiles),
+		cli.OnError("Run '!PROG! -h' for usage.\n"),
+	)
+
+	err := cmdLine.Parse(os.Args)
+
+	log.Error(err)
+	log.Info(cmdLine.GetHint())
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/macroblock/imed/pkg/cli"
+	"github.com/macroblock/imed/pkg/misc"
+	"github.com/macroblock/imed/pkg/tagname"
+	"github.com/macroblock/imed/pkg/zlog/loglevel"
+	"github.com/macroblock/imed/pkg/zlog/zlog"
+)
+
+var (
+	log = zlog.Instance("main")
+
+	flagManifest  string
+	flagSeries    bool
+	flagVocabFile = os.Getenv(tagname.EnvVocabularyFile)
+	flagSilent    bool
+	flagDontPause bool
+	flagFiles     []string
+
+	exitCode = 0
+)
+
+// collectFiles - files of the arguments, directories are walked recursively
+func collectFiles(args []string) ([]string, error) {
+	ret := []string{}
+	for _, arg := range args {
+		err := filepath.Walk(arg, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.Mode().IsRegular() {
+				ret = append(ret, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+// parseFiles - tagnames of the files, files that are not tagnames are skipped
+func parseFiles(paths []string) []*tagname.TTagname {
+	ret := []*tagname.TTagname{}
+	for _, path := range paths {
+		tn, err := tagname.NewFromFilename(path, false)
+		if tn == nil || tn.TitleKey() == "" {
+			if !flagSilent {
+				log.Info("skip: ", path)
+			}
+			continue
+		}
+		if err != nil && !flagSilent {
+			log.Warning(true, path, ": ", err)
+		}
+		ret = append(ret, tn)
+	}
+	return ret
+}
+
+// report - logs the results of the title or the season and returns the number of problems
+func report(header string, results []*tResult) int {
+	problems := 0
+	lines := []string{}
+	for _, r := range results {
+		if r.status != statusOK {
+			problems++
+		}
+		line := fmt.Sprintf("  %-12v %v", r.status, r.what)
+		if r.detail != "" {
+			line += " (" + r.detail + ")"
+		}
+		for _, path := range r.paths {
+			line += "\n      " + path
+		}
+		lines = append(lines, line)
+	}
+	switch {
+	case problems > 0:
+		log.Warning(true, header, ", ", problems, " problem(s)\n", strings.Join(lines, "\n"))
+	case !flagSilent:
+		log.Notice(header)
+		if len(lines) > 0 {
+			log.Info(strings.Join(lines, "\n"))
+		}
+	}
+	return problems
+}
+
+func mainFunc() error {
+	if len(flagFiles) == 0 {
+		return cli.ErrorNotEnoughArguments()
+	}
+	if flagVocabFile != "" {
+		if err := tagname.LoadVocabularyFile(flagVocabFile); err != nil {
+			return err
+		}
+	}
+	manifest, err := parseManifest(defaultManifest)
+	if err != nil {
+		return err
+	}
+	if flagManifest != "" {
+		manifest, err = loadManifest(flagManifest)
+		if err != nil {
+			return err
+		}
+	}
+
+	paths, err := collectFiles(flagFiles)
+	if err != nil {
+		return err
+	}
+	if flagSeries {
+		return auditSeries(parseFiles(paths))
+	}
+	titles := groupTitles(parseFiles(paths))
+	incomplete := 0
+	for _, title := range titles {
+		header := fmt.Sprintf("title %v: %v file(s)", title.key, len(title.list))
+		if report(header, manifest.check(title)) > 0 {
+			incomplete++
+		}
+	}
+	if incomplete > 0 {
+		// not a usage error, so the cli hint is not printed
+		exitCode = 1
+		log.Error(fmt.Errorf("%v of %v title(s) are incomplete", incomplete, len(titles)))
+	}
+	return nil
+}
+
+// auditSeries - reports problems of every season and prints the summary table
+func auditSeries(list []*tagname.TTagname) error {
+	seasons := groupSeasons(list)
+	problems := map[*tSeason]int{}
+	failed := 0
+	for _, s := range seasons {
+		header := fmt.Sprintf("season %v: %v episode file(s)", s.key(), len(s.files))
+		problems[s] = report(header, s.audit())
+		if problems[s] > 0 {
+			failed++
+		}
+	}
+	if len(seasons) > 0 {
+		if err := printSeasonTable(os.Stdout, seasons, problems); err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		exitCode = 1
+		log.Error(fmt.Errorf("%v of %v season(s) have problems", failed, len(seasons)))
+	}
+	return nil
+}
+
+func main() {
+	// setup log
+	newLogger := misc.NewSimpleLogger
+	if misc.IsTerminal() {
+		newLogger = misc.NewAnsiLogger
+	}
+	log.Add(
+		newLogger(loglevel.Warning.OrLower(), ""),
+		newLogger(loglevel.Info.Only().Include(loglevel.Notice.Only()), "~x\n"),
+	)
+
+	defer func() {
+		if log.State().Intersect(loglevel.Warning.OrLower()) != 0 && !flagDontPause {
+			misc.PauseTerminal()
+		}
+		if exitCode != 0 {
+			os.Exit(exitCode)
+		}
+	}()
+
+	// command line interface
+	cmdLine := cli.New("!PROG! the program that checks deliverables of titles and episodes of seasons in a folder tree.", mainFunc)
+	cmdLine.Elements(
+		cli.Usage("!PROG! {flags|<...>}"),
+		cli.Flag("-h --help    : help", cmdLine.PrintHelp).Terminator(),
+		cli.Flag("-m --manifest: a manifest file of required deliverables (default: film, trailer of the film format, poster, logo)", &flagManifest),
+		cli.Flag("-s --series  : audit episodes grouped by name and season instead (gaps, duplicates, different sdhd/atag/stag/vtag)", &flagSeries),
+		cli.Flag("--vocabulary-file: add words of the vocabulary file to the grammar (default: $"+tagname.EnvVocabularyFile+")", &flagVocabFile),
+		cli.Flag("-q --quiet   : quiet mode (display incomplete titles or seasons only)", &flagSilent),
+		cli.Flag("-k           : do not wait key press on errors", &flagDontPause),
+		cli.Flag(": files and folders to be checked", &flagFiles),
+		cli.OnError("Run '!PROG! -h' for usage.\n"),
+	)
+
+	err := cmdLine.Parse(os.Args)
+
+	log.Error(err)
+	log.Info(cmdLine.GetHint())
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/macroblock/imed/pkg/tagname"
+)
+
+const testManifest = `
+// a test package
+film
+trailer sdhd=film
+poster 1000x1500
+poster 600x600 sdhd=hd|4k
+logo
+gp 600x800 count=2
+`
+
+func testTitles(t *testing.T, names ...string) []*tTitle {
+	list := []*tagname.TTagname{}
+	for _, name := range names {
+		tn, err := tagname.NewFromString("", name, false)
+		if tn == nil {
+			t.Fatalf("%q: NewFromString() error: %v", name, err)
+		}
+		list = append(list, tn)
+	}
+	return groupTitles(list)
+}
+
+func TestParseManifest(t *testing.T) {
+	manifest, err := parseManifest(testManifest)
+	if err != nil {
+		t.Fatalf("parseManifest() error: %v", err)
+	}
+	want := tManifest{
+		{"film", "", 1, nil},
+		{"trailer", "", 1, []string{"film"}},
+		{"poster", "1000x1500", 1, nil},
+		{"poster", "600x600", 1, []string{"hd", "4k"}},
+		{"logo", "", 1, nil},
+		{"gp", "600x800", 2, nil},
+	}
+	if !reflect.DeepEqual(manifest, want) {
+		t.Errorf("have %+v\nwant %+v", manifest, want)
+	}
+	for _, src := range []string{"movie", "film count=0", "poster 1x1 2x2", "film\nfilm sdhd=hd"} {
+		if _, err := parseManifest(src); err == nil {
+			t.Errorf("parseManifest(%q): error expected", src)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	manifest, err := parseManifest(testManifest)
+	if err != nil {
+		t.Fatalf("parseManifest() error: %v", err)
+	}
+	titles := testTitles(t,
+		"the_movie_2019__hd_ar6e2.mp4",
+		"the_movie_2019__sd_ar2.trailer.mpg",
+		"the_movie_2019__hd_600x600.poster.jpg",
+		"the_movie_2019__hd_logo.poster.png",
+		"the_movie_2019__sd_logo.poster.png",
+		"the_movie_2019_600x800.jpg",
+		"the_movie_2019__hd_190x230.poster.jpg",
+		"other_2020__4k_ar6e2.mp4",
+	)
+	table := []struct {
+		key      string
+		statuses []string
+		whats    []string
+	}{
+		{"other__2020__", []string{statusOK, statusMissing, statusMissing, statusMissing, statusMissing, statusMissing},
+			[]string{"film", "trailer", "poster 1000x1500", "poster 600x600", "logo", "gp 600x800"}},
+		{"the_movie__2019__",
+			[]string{statusOK, statusMismatch, statusMissing, statusOK, statusDuplicate, statusMissing, statusUnlisted},
+			[]string{"film", "trailer", "poster 1000x1500", "poster 600x600", "logo", "gp 600x800", "poster 190x230"}},
+	}
+	if len(titles) != len(table) {
+		t.Fatalf("have %v titles, want %v", len(titles), len(table))
+	}
+	for i, v := range table {
+		if titles[i].key != v.key {
+			t.Errorf("title #%v: key %q, want %q", i, titles[i].key, v.key)
+			continue
+		}
+		statuses, whats := []string{}, []string{}
+		for _, r := range manifest.check(titles[i]) {
+			statuses = append(statuses, r.status)
+			whats = append(whats, r.what)
+		}
+		if !reflect.DeepEqual(statuses, v.statuses) || !reflect.DeepEqual(whats, v.whats) {
+			t.Errorf("%v:\nhave %v %q\nwant %v %q", v.key, statuses, whats, v.statuses, v.whats)
+		}
+	}
+}
+
+func TestSeriesAudit(t *testing.T) {
+	list := []*tagname.TTagname{}
+	for _, name := range []string{
+		"the_show_s01_01_2019__hd_ar6e2.mp4",
+		"the_show_s01_02-03_2019__hd_ar6e2.mp4",
+		"the_show_s01_05_2019__hd_ar6e2.mp4",
+		"the_show_s01_07_2019__hd_ar6e2.mp4",
+		"the_show_s01_07_2019__hd_ar2.mp4",
+		"the_show_s01_08_2019__sd_ar6e2.mp4",
+		"the_show_s01_2019__hd_1000x1500.poster.jpg",
+		"the_show_s01_01_2019__hd_ar2.trailer.mp4",
+		"the_show_s02_01_2020__hd_ar6e2.mp4",
+		"the_show_s02_02_2020__hd_ar6e2.mp4",
+		"the_show_s03_03_2021__hd_ar6e2.mp4",
+		"the_show_s04_01_2022__hd_ar6e2.mp4",
+		"the_show_s04_02a_2022__hd_ar6e2.mp4",
+		"the_show_s04_02b_2022__hd_ar6e2.mp4",
+		"the_show_s04_02b_2022__hd_ar2.mp4",
+	} {
+		tn, err := tagname.NewFromString("", name, false)
+		if tn == nil {
+			t.Fatalf("%q: NewFromString() error: %v", name, err)
+		}
+		list = append(list, tn)
+	}
+	seasons := groupSeasons(list)
+	table := []struct {
+		key      string
+		files    int
+		statuses []string
+		whats    []string
+	}{
+		{"the_show_s01", 6, []string{statusGap, statusDuplicate, statusInconsistent, statusInconsistent},
+			[]string{"e04, e06", "e07", "sdhd", "atag"}},
+		{"the_show_s02", 2, []string{}, []string{}},
+		{"the_show_s03", 1, []string{statusGap}, []string{"e01, e02"}},
+		// parts of an episode are not duplicates, a part delivered twice is
+		{"the_show_s04", 4, []string{statusDuplicate, statusInconsistent}, []string{"e02b", "atag"}},
+	}
+	if len(seasons) != len(table) {
+		t.Fatalf("have %v seasons, want %v", len(seasons), len(table))
+	}
+	for i, v := range table {
+		s := seasons[i]
+		if s.key() != v.key || len(s.files) != v.files {
+			t.Errorf("season #%v: %q of %v files, want %q of %v", i, s.key(), len(s.files), v.key, v.files)
+			continue
+		}
+		statuses, whats := []string{}, []string{}
+		for _, r := range s.audit() {
+			statuses = append(statuses, r.status)
+			whats = append(whats, r.what)
+		}
+		if !reflect.DeepEqual(statuses, v.statuses) || !reflect.DeepEqual(whats, v.whats) {
+			t.Errorf("%v:\nhave %v %q\nwant %v %q", v.key, statuses, whats, v.statuses, v.whats)
+		}
+	}
+	if d := seasons[0].audit()[1].detail; d != "atag ar6e2|ar2" {
+		t.Errorf("duplicate detail %q, want %q", d, "atag ar6e2|ar2")
+	}
+}
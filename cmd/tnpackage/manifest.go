@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/macroblock/imed/pkg/tagname"
+)
+
+// Manifest file format:
+//
+//	// comment
+//	film                     // a film of any format
+//	trailer  sdhd=film       // a trailer of the format of the film
+//	poster   1000x1500       // a poster of the size
+//	logo
+//	gp       600x800 count=2 // two gp posters of the size
+//
+// A line is a deliverable kind ('film', 'trailer', 'teaser', 'extra', 'audio',
+// 'subtitle', 'poster', 'logo' or 'gp'), an optional sizetag of posters and
+// options: 'count=N' - the number of files (default 1), 'sdhd=hd|4k' - allowed
+// formats, 'sdhd=film' - the format of the film of the title.
+// A file matches an entry of its kind and size first and an entry of its kind
+// without a size otherwise.
+
+var manifestKinds = map[string]bool{
+	"film": true, "trailer": true, "teaser": true, "extra": true, "audio": true, "subtitle": true,
+	"poster": true, "logo": true, "gp": true,
+}
+
+var defaultManifest = `
+film
+trailer sdhd=film
+poster
+logo
+`
+
+// tManifestEntry - a required deliverable
+type tManifestEntry struct {
+	kind  string
+	size  string
+	count int
+	sdhd  []string
+}
+
+func (o *tManifestEntry) String() string {
+	if o.size == "" {
+		return o.kind
+	}
+	return o.kind + " " + o.size
+}
+
+// tManifest -
+type tManifest []*tManifestEntry
+
+func parseManifest(src string) (tManifest, error) {
+	ret := tManifest{}
+	scanner := bufio.NewScanner(strings.NewReader(src))
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Text()
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		entry := &tManifestEntry{kind: fields[0], count: 1}
+		if !manifestKinds[entry.kind] {
+			return nil, fmt.Errorf("line %v: unknown deliverable kind %q", lineNo, entry.kind)
+		}
+		for _, field := range fields[1:] {
+			key, val, ok := strings.Cut(field, "=")
+			switch {
+			case !ok && entry.size == "":
+				entry.size = field
+			case key == "count":
+				n, err := strconv.Atoi(val)
+				if err != nil || n < 1 {
+					return nil, fmt.Errorf("line %v: invalid count %q", lineNo, val)
+				}
+				entry.count = n
+			case key == "sdhd":
+				entry.sdhd = strings.Split(val, "|")
+			default:
+				return nil, fmt.Errorf("line %v: unexpected %q", lineNo, field)
+			}
+		}
+		for _, e := range ret {
+			if e.kind == entry.kind && e.size == entry.size {
+				return nil, fmt.Errorf("line %v: %q is already listed", lineNo, entry.String())
+			}
+		}
+		ret = append(ret, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+func loadManifest(path string) (tManifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	ret, err := parseManifest(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", path, err)
+	}
+	return ret, nil
+}
+
+// deliverableKind - the manifest kind of the tagname and its sizetag
+func deliverableKind(tn *tagname.TTagname) (string, string) {
+	typ, _ := tn.GetType()
+	size, _ := tn.GetTag("sizetag")
+	switch typ {
+	case "poster":
+		if size == "logo" {
+			return "logo", ""
+		}
+	case "poster.logo":
+		return "logo", ""
+	case "poster.gp":
+		return "gp", size
+	}
+	return typ, size
+}
+
+// match - the entry the tagname is delivered as, nil if the manifest does not list it
+func (o tManifest) match(tn *tagname.TTagname) *tManifestEntry {
+	kind, size := deliverableKind(tn)
+	var ret *tManifestEntry
+	for _, e := range o {
+		if e.kind != kind {
+			continue
+		}
+		if e.size == size {
+			return e
+		}
+		if e.size == "" {
+			ret = e
+		}
+	}
+	return ret
+}
+
+// result statuses
+const (
+	statusOK        = "ok"
+	statusMissing   = "missing"
+	statusDuplicate = "duplicate"
+	statusMismatch  = "mismatch"
+	statusUnlisted  = "unlisted"
+)
+
+// tResult - a status of a manifest entry or of a file the manifest does not list
+type tResult struct {
+	status string
+	what   string
+	detail string
+	paths  []string
+}
+
+// tTitle - deliverables of a title
+type tTitle struct {
+	key  string
+	list []*tagname.TTagname
+}
+
+// groupTitles - groups the tagnames by TitleKey, titles and their files are sorted
+func groupTitles(list []*tagname.TTagname) []*tTitle {
+	byKey := map[string]*tTitle{}
+	for _, tn := range list {
+		key := tn.TitleKey()
+		if byKey[key] == nil {
+			byKey[key] = &tTitle{key: key}
+		}
+		byKey[key].list = append(byKey[key].list, tn)
+	}
+	ret := make([]*tTitle, 0, len(byKey))
+	for _, title := range byKey {
+		sort.Slice(title.list, func(i, j int) bool {
+			return title.list[i].Source() < title.list[j].Source()
+		})
+		ret = append(ret, title)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].key < ret[j].key
+	})
+	return ret
+}
+
+func sourcesOf(list []*tagname.TTagname) []string {
+	ret := []string{}
+	for _, tn := range list {
+		ret = append(ret, tn.Source())
+	}
+	return ret
+}
+
+// check - results of the manifest entries in the manifest order, then files the manifest does not list
+func (o tManifest) check(title *tTitle) []*tResult {
+	matched := map[*tManifestEntry][]*tagname.TTagname{}
+	unlisted := []*tagname.TTagname{}
+	filmSdhd := ""
+	for _, tn := range title.list {
+		e := o.match(tn)
+		if e == nil {
+			unlisted = append(unlisted, tn)
+			continue
+		}
+		matched[e] = append(matched[e], tn)
+		if sdhd, _ := tn.GetFormat(); e.kind == "film" && filmSdhd == "" {
+			filmSdhd = sdhd
+		}
+	}
+
+	ret := []*tResult{}
+	for _, e := range o {
+		want := e.sdhd
+		if len(want) == 1 && want[0] == "film" {
+			want = nil
+			if filmSdhd != "" {
+				want = []string{filmSdhd}
+			}
+		}
+		valid := []*tagname.TTagname{}
+		for _, tn := range matched[e] {
+			sdhd, err := tn.GetFormat()
+			if len(want) == 0 || err != nil || isInList(want, sdhd) {
+				valid = append(valid, tn)
+				continue
+			}
+			ret = append(ret, &tResult{statusMismatch, e.String(),
+				fmt.Sprintf("sdhd %v, want %v", sdhd, strings.Join(want, "|")), []string{tn.Source()}})
+		}
+		switch {
+		case len(valid) == 0 && len(matched[e]) == 0:
+			ret = append(ret, &tResult{statusMissing, e.String(), "", nil})
+		case len(valid) < e.count && len(matched[e]) == len(valid):
+			ret = append(ret, &tResult{statusMissing, e.String(),
+				fmt.Sprintf("have %v, want %v", len(valid), e.count), sourcesOf(valid)})
+		case len(valid) > e.count:
+			ret = append(ret, &tResult{statusDuplicate, e.String(),
+				fmt.Sprintf("have %v, want %v", len(valid), e.count), sourcesOf(valid)})
+		case len(valid) > 0:
+			ret = append(ret, &tResult{statusOK, e.String(), "", sourcesOf(valid)})
+		}
+	}
+	for _, tn := range unlisted {
+		kind, size := deliverableKind(tn)
+		what := strings.TrimSpace(kind + " " + size)
+		ret = append(ret, &tResult{statusUnlisted, what, "not in the manifest", []string{tn.Source()}})
+	}
+	return ret
+}
+
+func isInList(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
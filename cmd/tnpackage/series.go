@@ -0,0 +1,217 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/macroblock/imed/pkg/tagname"
+)
+
+// seriesTagTypes - tags every episode of a season must have the same
+var seriesTagTypes = []string{"sdhd", "atag", "stag", "vtag"}
+
+// audit statuses
+const (
+	statusGap          = "gap"
+	statusInconsistent = "inconsistent"
+)
+
+// tSeason - episodes of a season of a series
+type tSeason struct {
+	name      string
+	season    int // -1 - an unknown season ('sxx')
+	files     []*tagname.TTagname
+	byEpisode map[tagname.TEpisode][]*tagname.TTagname // parts of an episode ('05a', '05b') are separate keys
+}
+
+func (o *tSeason) key() string {
+	if o.season < 0 {
+		return o.name + "_sxx"
+	}
+	return fmt.Sprintf("%v_s%02d", o.name, o.season)
+}
+
+// groupSeasons - groups episodes of films by name and season, seasons are sorted.
+// Files without episodes (a whole season, trailers, posters) are not audited.
+func groupSeasons(list []*tagname.TTagname) []*tSeason {
+	byKey := map[string]*tSeason{}
+	for _, tn := range list {
+		if typ, _ := tn.GetType(); typ != "film" {
+			continue
+		}
+		season, episodes, err := tn.EpisodeParts()
+		if err != nil || len(episodes) == 0 {
+			continue
+		}
+		name, _ := tn.GetTag("name")
+		s := &tSeason{name: name, season: season, byEpisode: map[tagname.TEpisode][]*tagname.TTagname{}}
+		if byKey[s.key()] == nil {
+			byKey[s.key()] = s
+		}
+		s = byKey[s.key()]
+		s.files = append(s.files, tn)
+		for _, e := range episodes {
+			s.byEpisode[e] = append(s.byEpisode[e], tn)
+		}
+	}
+	ret := make([]*tSeason, 0, len(byKey))
+	for _, s := range byKey {
+		sort.Slice(s.files, func(i, j int) bool {
+			return s.files[i].Source() < s.files[j].Source()
+		})
+		ret = append(ret, s)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].name != ret[j].name {
+			return ret[i].name < ret[j].name
+		}
+		return ret[i].season < ret[j].season
+	})
+	return ret
+}
+
+// episodes - sorted present episodes and parts of episodes
+func (o *tSeason) episodes() []tagname.TEpisode {
+	ret := make([]tagname.TEpisode, 0, len(o.byEpisode))
+	for e := range o.byEpisode {
+		ret = append(ret, e)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Num != ret[j].Num {
+			return ret[i].Num < ret[j].Num
+		}
+		return ret[i].Part < ret[j].Part
+	})
+	return ret
+}
+
+// numbers - sorted unique numbers of the present episodes
+func (o *tSeason) numbers() []int {
+	ret := []int{}
+	for _, e := range o.episodes() {
+		if len(ret) == 0 || ret[len(ret)-1] != e.Num {
+			ret = append(ret, e.Num)
+		}
+	}
+	return ret
+}
+
+// gaps - episodes that are absent from the first episode of the season up to the last present one
+func (o *tSeason) gaps() []int {
+	list := o.numbers()
+	ret := []int{}
+	next := 1
+	for _, n := range list {
+		for ; next < n; next++ {
+			ret = append(ret, next)
+		}
+		next = n + 1
+	}
+	return ret
+}
+
+// tagValue - all the values of the tag type of the file as a single string, '-' if there are none
+func tagValue(tn *tagname.TTagname, typ string) string {
+	list := tn.GetTags(typ)
+	if len(list) == 0 {
+		return "-"
+	}
+	return strings.Join(list, "+")
+}
+
+// commonValue - the value of the tag type most episodes have (the least one of equally frequent values)
+func (o *tSeason) commonValue(typ string) string {
+	count := map[string]int{}
+	for _, tn := range o.files {
+		count[tagValue(tn, typ)]++
+	}
+	ret := ""
+	for val, n := range count {
+		if ret == "" || n > count[ret] || n == count[ret] && val < ret {
+			ret = val
+		}
+	}
+	return ret
+}
+
+// diffTags - tag types the files have different values of, e.g. 'atag ar2|ar6e2'
+func diffTags(list []*tagname.TTagname) []string {
+	ret := []string{}
+	for _, typ := range seriesTagTypes {
+		vals := []string{}
+		for _, tn := range list {
+			if val := tagValue(tn, typ); !isInList(vals, val) {
+				vals = append(vals, val)
+			}
+		}
+		if len(vals) > 1 {
+			ret = append(ret, typ+" "+strings.Join(vals, "|"))
+		}
+	}
+	return ret
+}
+
+func formatEpisodes(list []int) string {
+	ret := []string{}
+	for _, n := range list {
+		ret = append(ret, tagname.TEpisode{Num: n}.String())
+	}
+	return strings.Join(ret, ", ")
+}
+
+// audit - gaps, duplicate episodes (or parts of episodes) and episodes whose tags differ from the ones of most episodes
+func (o *tSeason) audit() []*tResult {
+	ret := []*tResult{}
+	if gaps := o.gaps(); len(gaps) > 0 {
+		ret = append(ret, &tResult{statusGap, formatEpisodes(gaps), "", nil})
+	}
+	for _, e := range o.episodes() {
+		list := o.byEpisode[e]
+		if len(list) < 2 {
+			continue
+		}
+		detail := "same tags"
+		if diff := diffTags(list); len(diff) > 0 {
+			detail = strings.Join(diff, ", ")
+		}
+		ret = append(ret, &tResult{statusDuplicate, e.String(), detail, sourcesOf(list)})
+	}
+	for _, typ := range seriesTagTypes {
+		common := o.commonValue(typ)
+		for _, tn := range o.files {
+			if val := tagValue(tn, typ); val != common {
+				ret = append(ret, &tResult{statusInconsistent, typ,
+					fmt.Sprintf("%v, most episodes have %v", val, common), []string{tn.Source()}})
+			}
+		}
+	}
+	return ret
+}
+
+// printSeasonTable - a summary line of every season
+func printSeasonTable(w io.Writer, seasons []*tSeason, problems map[*tSeason]int) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	head := []string{"series", "season", "episodes", "range", "gaps", "problems"}
+	head = append(head, seriesTagTypes...)
+	fmt.Fprintln(tw, strings.Join(head, "\t"))
+	for _, s := range seasons {
+		season := "sxx"
+		if s.season >= 0 {
+			season = fmt.Sprintf("s%02d", s.season)
+		}
+		list := s.numbers()
+		episodes := fmt.Sprintf("e%02d", list[0])
+		if len(list) > 1 {
+			episodes += fmt.Sprintf("-e%02d", list[len(list)-1])
+		}
+		row := []string{s.name, season, fmt.Sprint(len(list)), episodes, fmt.Sprint(len(s.gaps())), fmt.Sprint(problems[s])}
+		for _, typ := range seriesTagTypes {
+			row = append(row, s.commonValue(typ))
+		}
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
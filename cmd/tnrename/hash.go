@@ -0,0 +1,88 @@
+package main
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// tHashEntry - a file that has the hashtag
+type tHashEntry struct {
+	path string
+	key  string // a title key the hashtag is generated from
+}
+
+var (
+	hashIndex  = map[string][]tHashEntry{} // by the hashtag of the source (the rewritten one with --rehash)
+	staleCount = 0
+)
+
+// verifyHash - reports a stale hashtag of the job files and plans its rewrite if --rehash is set
+func verifyHash(job *tJob, schema string) {
+	for _, tn := range job.list {
+		if tn == nil {
+			log.Error(job.err, job.path+": cannot verify hashtag")
+			continue
+		}
+		have, want := tn.HashTags()
+		if have == "" {
+			if !flagSilent {
+				log.Info("no hashtag: " + tn.Source())
+			}
+			continue
+		}
+		entry := tHashEntry{tn.Source(), tn.HashKey()}
+		if err := tn.VerifyHash(); err == nil {
+			hashIndex[have] = append(hashIndex[have], entry)
+			if !flagSilent {
+				log.Info("ok: " + tn.Source())
+			}
+			continue
+		}
+		staleCount++
+		log.Warning(true, "stale hashtag: ", tn.Source(), " (", have, ", want ", want, ")")
+		if !flagRehash {
+			hashIndex[have] = append(hashIndex[have], entry)
+			continue
+		}
+		// the file is going to have the new hashtag
+		hashIndex[want] = append(hashIndex[want], entry)
+		name, err := tn.Rehash()
+		if err != nil {
+			log.Error(err)
+			continue
+		}
+		newPath := filepath.Join(filepath.Dir(tn.Source()), name)
+		if flagDoRename {
+			planRename(tn, newPath, "")
+		}
+		log.Notice("rehash > ", newPath)
+	}
+}
+
+// reportHashCollisions - reports hashtags shared by different titles and returns their number
+func reportHashCollisions() int {
+	hashes := make([]string, 0, len(hashIndex))
+	for h := range hashIndex {
+		hashes = append(hashes, h)
+	}
+	sort.Strings(hashes)
+	ret := 0
+	for _, h := range hashes {
+		list := hashIndex[h]
+		keys := map[string]bool{}
+		for _, e := range list {
+			keys[e.key] = true
+		}
+		if len(keys) < 2 {
+			continue
+		}
+		ret++
+		lines := []string{}
+		for _, e := range list {
+			lines = append(lines, "\n    "+e.path+" ("+e.key+")")
+		}
+		log.Warning(true, "hashtag ", h, " is shared by different titles:", strings.Join(lines, ""))
+	}
+	return ret
+}
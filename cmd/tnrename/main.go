@@ -5,11 +5,17 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/macroblock/imed/pkg/cli"
+	"github.com/macroblock/imed/pkg/ffcache"
+	"github.com/macroblock/imed/pkg/journal"
 	"github.com/macroblock/imed/pkg/misc"
+	"github.com/macroblock/imed/pkg/renplan"
 	"github.com/macroblock/imed/pkg/tagname"
 	"github.com/macroblock/imed/pkg/zlog/loglevel"
 	"github.com/macroblock/imed/pkg/zlog/zlog"
@@ -20,23 +26,118 @@ var (
 	retif     = log.Catcher()
 	logFilter = loglevel.Warning.OrLower()
 
-	flagStrict     bool
-	flagDeep       bool
-	flagForce      string
-	flagScriptFile string
-	flagFileList   string
-	flagDoRename   bool
-	flagAddHash    bool
-	flagReport     bool
-	flagDontPause  bool
-	flagSilent     bool
-	flagFiles      []string
-
-	globalTags map[string]map[string]bool
+	flagStrict        bool
+	flagDeep          bool
+	flagForce         string
+	flagScriptFile    string
+	flagSchemaFiles   []string
+	flagProfileFiles  []string
+	flagProfile       string
+	flagFileList      string
+	flagDoRename      bool
+	flagAddHash       bool
+	flagReport        bool
+	flagDontPause     bool
+	flagSilent        bool
+	flagFiles         []string
+	flagDiag          bool
+	flagSuggest       bool
+	flagAutoTag       bool
+	flagIgnore        []string
+	flagNoCache       bool
+	flagVocabFile     = os.Getenv(tagname.EnvVocabularyFile)
+	flagListVocab     bool
+	flagVerifyHash    bool
+	flagRehash        bool
+	flagClearCache    bool
+	flagJobs          int
+	flagFormat        string
+	flagJournal       string
+	flagUndo          string
+	flagResolveCycles bool
+	flagWatch         string
+	flagWatchInterval = 5
+	flagWatchSettle   = 10
+	flagScriptTest    string
+	flagScriptModules []string
+	flagScriptTimeout = 60
+	flagScriptAllocs  = 5000000
+
+	globalTags = &tTagReport{}
+	exitCode   = 0
 )
 
-func doProcess(path string, schema string, isDeepCheck bool, script *tagname.TScript) {
+// tJob - a result of the file preprocessing
+type tJob struct {
+	path        string
+	list        []*tagname.TTagname
+	err         error
+	suggestions []*tagname.TSuggestion
+	suggestErr  error
+	isScript    bool
+}
+
+// prepare - parses and checks the file. It does not log anything, so it can be run concurrently.
+func prepare(path string, schema string, isDeepCheck bool, script *tagname.TScript) *tJob {
+	job := &tJob{path: path, isScript: script != nil}
+	if script != nil {
+		job.list, job.err = script.Run(path)
+	} else {
+		tn, err := tagname.NewFromFilename(path, isDeepCheck && !flagAutoTag)
+		if tn != nil && flagAutoTag {
+			err = autoTag(tn, isDeepCheck)
+		}
+		if tn != nil && len(flagIgnore) > 0 {
+			err = tagname.Diagnostics(err).Exclude(flagIgnore...).Err()
+		}
+		job.list = append(job.list, tn)
+		job.err = err
+	}
+	if flagSuggest && job.err != nil && script == nil {
+		job.suggestions, job.suggestErr = tagname.Suggest(filepath.Base(path), schema)
+	}
+	return job
+}
+
+// prepareAll - runs prepare on n workers and calls fn with the results in the input order
+func prepareAll(paths []string, n int, schema string, isDeepCheck bool, script *tagname.TScript, fn func(*tJob)) {
+	if n <= 1 {
+		for _, path := range paths {
+			fn(prepare(path, schema, isDeepCheck, script))
+		}
+		return
+	}
+	results := make([]chan *tJob, len(paths))
+	for i := range results {
+		results[i] = make(chan *tJob, 1)
+	}
+	indices := make(chan int)
+	go func() {
+		for i := range paths {
+			indices <- i
+		}
+		close(indices)
+	}()
+	for w := 0; w < n; w++ {
+		// a compiled script keeps its globals, so every worker needs its own copy
+		s := script
+		if s != nil {
+			s = script.Clone()
+		}
+		go func() {
+			for i := range indices {
+				results[i] <- prepare(paths[i], schema, isDeepCheck, s)
+			}
+		}()
+	}
+	for _, ch := range results {
+		fn(<-ch)
+	}
+}
+
+func doProcess(job *tJob, schema string) {
 	defer retif.Catch()
+	path := job.path
 	errPrefix := ""
 	if flagSilent {
 		errPrefix = "\n" + path + "\n"
@@ -46,50 +147,30 @@ func doProcess(path string, schema string, isDeepCheck bool, script *tagname.TSc
 		log.Info("rename: " + path)
 	}
 
-	var list []*tagname.TTagname
-	var err error
+	list, err := job.list, job.err
 
-	if script != nil {
-		list, err = script.Run(path)
-	} else {
-		var tn *tagname.TTagname
-		tn, err = tagname.NewFromFilename(path, isDeepCheck)
-		list = append(list, tn)
+	if flagDiag {
+		printDiagnostics(path, err)
+	}
+	if flagSuggest && err != nil && !job.isScript {
+		printSuggestions(path, job.suggestions, job.suggestErr)
 	}
 
 	if flagReport {
-		for _, tn := range list {
-			if tn == nil {
-				continue
-			}
-			if globalTags == nil {
-				globalTags = map[string]map[string]bool{}
-			}
-			tags := tn.ListTags()
-			for _, t := range tags {
-				if _, ok := globalTags[t]; !ok {
-					globalTags[t] = map[string]bool{}
-				}
-				dst := globalTags[t]
-				list := tn.GetTags(t)
-				for _, v := range list {
-					dst[v] = true
-				}
-				// fmt.Printf("%16v : %v\n", v, list)
-			}
-		}
+		globalTags.Add(list)
 	}
 
+	if list := tagname.SchemaErrors(err); len(list) > 0 {
+		err = fmt.Errorf("%v", formatParseErrors(list, misc.IsTerminal()))
+	}
 	retif.Error(err, errPrefix+"whilest preprocess")
 
 	for _, tn := range list {
-		srcPath := tn.Source()
 		newPath, err := tn.ConvertTo(schema)
 		retif.Error(err, errPrefix+"cannot convert to '"+schema+"'")
 
 		if flagDoRename {
-			err = os.Rename(srcPath, newPath)
-			retif.Error(err, fmt.Sprintf(errPrefix+"cannot rename %v -> %v", srcPath, newPath))
+			planRename(tn, newPath, schema)
 		}
 
 		if !flagSilent {
@@ -98,22 +179,134 @@ func doProcess(path string, schema string, isDeepCheck bool, script *tagname.TSc
 	}
 }
 
-func printTags() {
-	if globalTags == nil {
+var (
+	renameJournal *journal.TJournal
+	renamePlan    = renplan.New()
+)
+
+// planRename - adds the rename to the plan. Renames are applied by applyPlan.
+func planRename(tn *tagname.TTagname, newPath string, schema string) int {
+	if schema == "" {
+		schema = tn.Schema()
+	}
+	return renamePlan.Add(tn.Source(), newPath, schema)
+}
+
+// applyPlan - renames files if there are no conflicts and records renames to the journal
+func applyPlan() error {
+	for _, c := range renamePlan.Errors(flagResolveCycles) {
+		if c.Kind == renplan.ConflictCycle {
+			log.Warning(true, "use --resolve-cycles to apply renames that form a cycle")
+			break
+		}
+	}
+	return renamePlan.Apply(flagResolveCycles, renameJournal.Rename)
+}
+
+// autoTag - fills absent tags from the file and checks the result
+func autoTag(tn *tagname.TTagname, isDeepCheck bool) error {
+	conflicts, err := tn.Complete()
+	if err != nil {
+		return err
+	}
+	list := append(conflicts, tn.Diagnose(isDeepCheck)...)
+	return list.Err()
+}
+
+func printDiagnostics(path string, err error) {
+	src := filepath.Base(path)
+	for _, d := range tagname.Diagnostics(err) {
+		log.Info("  " + formatDiagnostic(src, d))
+	}
+}
+
+// formatDiagnostic - a single line description of the diagnostic of the src filename
+func formatDiagnostic(src string, d *tagname.TDiagnostic) string {
+	pos := ""
+	if d.Span.IsValid() && d.Span.End <= len(src) {
+		pos = fmt.Sprintf(" at %v:%v %q", d.Span.Start, d.Span.End, src[d.Span.Start:d.Span.End])
+	}
+	return fmt.Sprintf("[%v] %v tag=%q expected=%q actual=%q%v", d.Code, d.Severity, d.TagType, d.Expected, d.Actual, pos)
+}
+
+// formatParseErrors - the farthest failure of every schema with an excerpt of the filename,
+// the matched part is green and the rest is red if isColored is set
+func formatParseErrors(list []*tagname.TSchemaError, isColored bool) string {
+	b := &strings.Builder{}
+	b.WriteString("does not parse under any schema:")
+	for _, se := range list {
+		fmt.Fprintf(b, "\n  %-6q %v\n", se.Schema, se)
+		if !isColored {
+			b.WriteString(se.Excerpt("    "))
+			continue
+		}
+		rest := strings.TrimPrefix(se.Source, se.Matched()+se.Char())
+		fmt.Fprintf(b, "    %v%v%v%v%v%v%v\n", misc.Color(misc.ColorGreen), se.Matched(),
+			misc.Color(misc.ColorBold, misc.ColorRed), se.Char(), misc.Color(misc.ColorRed), rest, misc.Color())
+		b.WriteString(strings.TrimPrefix(se.Excerpt("    "), "    "+se.Source+"\n"))
+	}
+	return b.String()
+}
+
+func printSuggestions(path string, list []*tagname.TSuggestion, err error) {
+	dir := strings.TrimSuffix(path, filepath.Base(path))
+	if err != nil {
+		log.Warning(err)
+		return
+	}
+	if len(list) == 0 {
+		log.Info("  no suggestions")
+		return
+	}
+	for _, s := range list {
+		log.Notice("  suggest: ", dir+s.Name, "  (", strings.Join(s.Fixes, ", "), ")")
+	}
+}
+
+// tTagReport - a set of values of every tag type
+type tTagReport struct {
+	mtx  sync.Mutex
+	tags map[string]map[string]bool
+}
+
+// Add -
+func (o *tTagReport) Add(list []*tagname.TTagname) {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	for _, tn := range list {
+		if tn == nil {
+			continue
+		}
+		if o.tags == nil {
+			o.tags = map[string]map[string]bool{}
+		}
+		for _, t := range tn.ListTags() {
+			if _, ok := o.tags[t]; !ok {
+				o.tags[t] = map[string]bool{}
+			}
+			dst := o.tags[t]
+			for _, v := range tn.GetTags(t) {
+				dst[v] = true
+			}
+		}
+	}
+}
+
+// Print -
+func (o *tTagReport) Print() {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	if o.tags == nil {
 		return
 	}
 	taglist := []string{}
-	for tag, _ := range globalTags {
+	for tag := range o.tags {
 		taglist = append(taglist, tag)
 	}
 	sort.Strings(taglist)
 	for _, tag := range taglist {
-		valmap, ok := globalTags[tag]
-		if !ok {
-			panic("unreachable")
-		}
 		vallist := []string{}
-		for key, _ := range valmap {
+		for key := range o.tags[tag] {
 			vallist = append(vallist, key)
 		}
 		sort.Strings(vallist)
@@ -134,16 +327,82 @@ func readFile(filename string) (string, error) {
 	return string(ret), nil
 }
 
+// scriptTest - runs the script on the fixture cases and prints a diff of mismatches
+func scriptTest(path string, schema string, script *tagname.TScript) error {
+	if script == nil {
+		return fmt.Errorf("--script-test requires a script (-t)")
+	}
+	cases, err := tagname.LoadScriptFixtures(path)
+	if err != nil {
+		return err
+	}
+	results := tagname.TestScript(script, cases, schema)
+	fmt.Printf("--- %v (expected)\n+++ %v (actual)\n", path, flagScriptFile)
+	if failed := tagname.WriteScriptReport(os.Stdout, results); failed > 0 {
+		// not a usage error, so the cli hint is not printed
+		exitCode = 1
+		log.Error(fmt.Errorf("%v of %v script cases failed", failed, len(results)))
+	}
+	return nil
+}
+
 func mainFunc() error {
 
-	if len(flagFiles) == 0 && flagFileList == "" {
+	if flagNoCache {
+		ffcache.SetDir("")
+	}
+	if flagClearCache {
+		if err := ffcache.Clear(); err != nil {
+			return err
+		}
+		if len(flagFiles) == 0 && flagFileList == "" {
+			return nil
+		}
+	}
+	if flagUndo != "" {
+		return journal.Undo(flagUndo, func(e *journal.TEntry) {
+			log.Notice("undo > ", e.Source)
+		})
+	}
+	if flagVocabFile != "" {
+		if err := tagname.LoadVocabularyFile(flagVocabFile); err != nil {
+			return err
+		}
+	}
+	if flagListVocab {
+		for _, name := range tagname.VocabularyLists() {
+			fmt.Printf("%v = %v\n", name, strings.Join(tagname.VocabularyList(name), " "))
+		}
+		if len(flagFiles) == 0 && flagFileList == "" {
+			return nil
+		}
+	}
+	if len(flagFiles) == 0 && flagFileList == "" && flagWatch == "" && flagScriptTest == "" {
 		return cli.ErrorNotEnoughArguments()
 	}
 
-	switch flagForce {
-	default:
-		return fmt.Errorf("Unknown schema %q", flagForce)
-	case "old", "rt", "":
+	for _, path := range flagSchemaFiles {
+		_, err := tagname.LoadSchemaFile(path)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, path := range flagProfileFiles {
+		if _, err := tagname.LoadCheckProfiles(path); err != nil {
+			return err
+		}
+	}
+	if flagProfile != "" {
+		if err := tagname.SetCheckProfile(flagProfile); err != nil {
+			return fmt.Errorf("%v (registered: %v)", err, strings.Join(tagname.CheckProfiles(), ", "))
+		}
+	}
+
+	if flagForce != "" {
+		if _, err := tagname.Schema(flagForce); err != nil {
+			return fmt.Errorf("Unknown schema %q (registered: %v)", flagForce, strings.Join(tagname.Schemas(), ", "))
+		}
 	}
 
 	var script *tagname.TScript
@@ -153,17 +412,22 @@ func mainFunc() error {
 		if err != nil {
 			return err
 		}
-		s, err := tagname.NewScript(text)
+		s, err := tagname.NewScriptWithOptions(text, tagname.TScriptOptions{
+			Modules:   flagScriptModules,
+			Timeout:   time.Duration(flagScriptTimeout) * time.Second,
+			MaxAllocs: int64(flagScriptAllocs),
+		})
 		if err != nil {
 			return fmt.Errorf("NewScript: %v", err)
 		}
 		script = s
 	}
 
-	for _, path := range flagFiles {
-		doProcess(path, flagForce, flagDeep, script)
+	if flagScriptTest != "" {
+		return scriptTest(flagScriptTest, flagForce, script)
 	}
 
+	paths := append([]string(nil), flagFiles...)
 	if flagFileList != "" {
 		file, err := os.Open(flagFileList)
 		if err != nil {
@@ -175,12 +439,73 @@ func mainFunc() error {
 		for scanner.Scan() {
 			line := scanner.Text()
 			line = strings.TrimSpace(line)
-			doProcess(line, flagForce, flagDeep, script)
+			paths = append(paths, line)
+		}
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+	}
+
+	if flagDoRename || flagWatch != "" {
+		path := flagJournal
+		if path == "" {
+			p, err := journal.DefaultPath("tnrename")
+			if err != nil {
+				return err
+			}
+			path = p
+		}
+		renameJournal = journal.Open(path, "tnrename")
+		defer func() {
+			if !renameJournal.IsEmpty() && flagFormat == "" {
+				log.Notice("journal: ", renameJournal.Path())
+			}
+			log.Error(renameJournal.Close())
+		}()
+	}
+
+	if flagWatch != "" {
+		return watchDir(flagWatch, flagForce, script)
+	}
+
+	process := doProcess
+	if flagFormat != "" {
+		w, err := newRecordWriter(flagFormat, os.Stdout)
+		if err != nil {
+			return err
+		}
+		recordWriter = w
+		process = writeRecord
+	}
+	if flagVerifyHash || flagRehash {
+		process = verifyHash
+	}
+	prepareAll(paths, flagJobs, flagForce, flagDeep, script, func(job *tJob) {
+		process(job, flagForce)
+	})
+	if flagVerifyHash || flagRehash {
+		if n := reportHashCollisions(); n > 0 || staleCount > 0 && !(flagRehash && flagDoRename) {
+			// not a usage error, so the cli hint is not printed
+			exitCode = 1
+			log.Error(fmt.Errorf("%v stale hashtag(s), %v hashtag(s) shared by different titles", staleCount, n))
+		}
+	}
+	if flagDoRename {
+		err := applyPlan()
+		if recordWriter != nil {
+			writePlannedRecords(err)
+		} else {
+			log.Error(err)
+		}
+	}
+	if recordWriter != nil {
+		if err := recordWriter.Flush(); err != nil {
+			return err
 		}
 	}
 
 	if flagReport {
-		printTags()
+		globalTags.Print()
 	}
 	return nil
 }
@@ -200,6 +525,9 @@ func main() {
 		if (log.State().Intersect(loglevel.Warning.OrLower()) != 0 || flagReport) && !flagDontPause {
 			misc.PauseTerminal()
 		}
+		if exitCode != 0 {
+			os.Exit(exitCode)
+		}
 	}()
 
 	// command line interface
@@ -209,13 +537,38 @@ func main() {
 		// cli.Hint("Use '!PROG! help <flag>' for more information."),
 		cli.Flag("-h --help   : help", cmdLine.PrintHelp).Terminator(), // Why is this works ?
 		cli.Flag("-s --strict : raise an error on an unknown tag.", &flagStrict),
-		cli.Flag("-d --deep   : raise an error on a tag that does not reflect to a real format.", &flagDeep),
-		cli.Flag("-f --force  : force to rename to a schema ('old' and 'rt' is supported)", &flagForce),
+		cli.Flag("-d --deep   : raise an error on a tag that does not reflect to a real format (video limits are built in, image limits need a profile, e.g. 'images' of platforms.profile).", &flagDeep),
+		cli.Flag("-f --force  : force to rename to a registered schema ('old', 'rt' or a loaded one)", &flagForce),
+		cli.Flag("-S --schema-file : load a schema definition file (can be repeated)", &flagSchemaFiles),
+		cli.Flag("-P --profile-file: load a check profile file (can be repeated)", &flagProfileFiles),
+		cli.Flag("--profile   : check tags with the rules of the loaded profile added", &flagProfile),
 		cli.Flag("-n --do-rename: do rename files)", &flagDoRename),
 		cli.Flag("-r --report : print cumulative report", &flagReport),
 		cli.Flag("-k          : do not wait key press on errors or report", &flagDontPause),
 		cli.Flag("-q --quiet  : quiet mode (display errors only)", &flagSilent),
 		cli.Flag("-t --script : a script file path to run", &flagScriptFile),
+		cli.Flag("--script-module: a module the script can import (can be repeated, replaces the default list: "+strings.Join(tagname.DefaultScriptModules, ", ")+")", &flagScriptModules),
+		cli.Flag("--script-timeout: max seconds a script can run on a single file, 0 - no limit (default 60)", &flagScriptTimeout),
+		cli.Flag("--script-max-allocs: max number of objects a script can allocate on a single file, 0 - no limit (default 5000000)", &flagScriptAllocs),
+		cli.Flag("--script-test: run the script (-t) on inputs of the fixture file and report mismatches with expected outputs", &flagScriptTest),
+		cli.Flag("-D --diag   : print detailed diagnostics (code, tag, expected/actual value, position)", &flagDiag),
+		cli.Flag("-i --ignore : ignore diagnostics with the code (can be repeated)", &flagIgnore),
+		cli.Flag("-g --suggest: suggest corrected names for invalid filenames", &flagSuggest),
+		cli.Flag("-a --autotag: fill in absent tags (sdhd, atag, stag, sizetag, ext) from the media file", &flagAutoTag),
+		cli.Flag("-j --jobs   : number of files to parse and check concurrently (default 1)", &flagJobs),
+		cli.Flag("-F --format : print one record per input file to stdout ('json' lines or 'csv') instead of the log", &flagFormat),
+		cli.Flag("-J --journal: a journal file to record renames to (default: a new file in "+journal.EnvJournalDir+" or the user cache dir)", &flagJournal),
+		cli.Flag("-u --undo   : revert renames recorded in the journal file", &flagUndo),
+		cli.Flag("--resolve-cycles: apply renames that form a cycle (a->b, b->a) through temporary names", &flagResolveCycles),
+		cli.Flag("-w --watch  : watch the directory and process files that have stopped growing; results are moved to 'done/' or 'failed/' subfolders", &flagWatch),
+		cli.Flag("--watch-interval: seconds between scans of the watched directory (default 5)", &flagWatchInterval),
+		cli.Flag("--watch-settle: seconds a file must not change to be processed (default 10)", &flagWatchSettle),
+		cli.Flag("--vocabulary-file: add words of the vocabulary file to the grammar (default: $"+tagname.EnvVocabularyFile+")", &flagVocabFile),
+		cli.Flag("--vocabulary: print the vocabulary lists (studios, exclusive tags, age ratings)", &flagListVocab),
+		cli.Flag("--verify-hash: list files whose hashtag does not match the title and hashtags shared by different titles", &flagVerifyHash),
+		cli.Flag("--rehash    : like --verify-hash and rewrite stale hashtags (with -n)", &flagRehash),
+		cli.Flag("--no-cache  : do not use the ffprobe cache (or set "+ffcache.EnvCacheDir+"=off)", &flagNoCache),
+		cli.Flag("--clear-cache: remove all the ffprobe cache entries", &flagClearCache),
 		cli.Flag("-l --filelist   : specifies a file that contains list of files to process", &flagFileList),
 		cli.Flag(": files to be processed", &flagFiles),
 		cli.OnError("Run '!PROG! -h' for usage.\n"),
@@ -0,0 +1,47 @@
+// check profiles of delivery platforms
+// usage: tnrename -P platforms.profile --profile agerated {files}
+
+// agetag is mandatory for films, trailers and teasers
+[agerated]
+film.must = agetag
+
+// agetag is forbidden for every type
+[noage]
+invalid = agetag
+
+// agerated plus a quality tag and several meta tags on posters
+[agerated.strict]
+extends          = agerated
+film.must        = qtag
+poster.nonunique = mtag
+
+// dubs must be labeled with an age rating as well
+[agerated.dubs]
+extends    = agerated
+audio.must = agetag
+
+// image limits of the deep check: rgb posters as baseline jpeg, logos as png with transparency
+[images]
+poster.formats     = jpeg
+poster.colormodels = rgb
+poster.progressive = no
+gp.formats         = jpeg
+gp.colormodels     = rgb
+gp.progressive     = no
+logo.formats       = png
+logo.alpha         = yes
+
+// web delivery limits on top of the image ones
+[images.web]
+extends            = images
+poster.maxsize     = 2m
+poster.dpi         = 72
+logo.maxsize       = 500k
+
+// 4k extras may be sdr, the built-in video limits require hdr for 4k
+[sdr4k.extras]
+extra.4k.pixfmts   = yuv420p yuv420p10le
+extra.4k.bitdepth  = 8
+extra.4k.primaries = bt709 bt2020
+extra.4k.transfers = bt709 smpte2084 arib-std-b67
+extra.4k.hdr       = no
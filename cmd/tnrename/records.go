@@ -0,0 +1,213 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/macroblock/imed/pkg/tagname"
+)
+
+// tRecord - a machine readable result of processing of one input file
+type tRecord struct {
+	Input       string               `json:"input"`
+	OK          bool                 `json:"ok"`
+	Targets     []string             `json:"targets"`
+	Renamed     bool                 `json:"renamed"`
+	Error       string               `json:"error,omitempty"`
+	Diagnostics tagname.TDiagnostics `json:"diagnostics"`
+	Tagnames    []*tagname.TTagname  `json:"tagnames"`
+}
+
+// tag types that have their own csv column
+var csvTagColumns = []string{"type", "name", "sname", "ename", "sxx", "exx", "year", "sdhd", "qtag", "atag", "stag", "agetag", "sizetag", "hashtag", "ext"}
+
+// tRecordWriter -
+type tRecordWriter interface {
+	Write(rec *tRecord) error
+	Flush() error
+}
+
+func newRecordWriter(format string, w io.Writer) (tRecordWriter, error) {
+	switch format {
+	case "json":
+		return &tJSONWriter{enc: json.NewEncoder(w)}, nil
+	case "csv":
+		return &tCSVWriter{w: csv.NewWriter(w)}, nil
+	}
+	return nil, fmt.Errorf("unsupported output format %q (want 'json' or 'csv')", format)
+}
+
+// tJSONWriter - writes one JSON object per line
+type tJSONWriter struct {
+	enc *json.Encoder
+}
+
+func (o *tJSONWriter) Write(rec *tRecord) error {
+	return o.enc.Encode(rec)
+}
+
+func (o *tJSONWriter) Flush() error {
+	return nil
+}
+
+// tCSVWriter - writes one row per input file. Values of the first tagname are used if a script returns several.
+type tCSVWriter struct {
+	w        *csv.Writer
+	isHeader bool
+}
+
+func (o *tCSVWriter) Write(rec *tRecord) error {
+	if !o.isHeader {
+		o.isHeader = true
+		head := []string{"input", "ok", "targets", "renamed", "schema"}
+		head = append(head, csvTagColumns...)
+		head = append(head, "other_tags", "resolution", "sar", "codes", "error")
+		if err := o.w.Write(head); err != nil {
+			return err
+		}
+	}
+	var tn *tagname.TTagname
+	if len(rec.Tagnames) > 0 {
+		tn = rec.Tagnames[0]
+	}
+	row := []string{rec.Input, fmt.Sprint(rec.OK), strings.Join(rec.Targets, ";"), fmt.Sprint(rec.Renamed), ""}
+	resolution, sar, other := "", "", ""
+	if tn != nil {
+		row[4] = tn.Schema()
+		if format, err := tn.Describe(); err == nil {
+			resolution = format.Resolution().String()
+			sar = format.Sar
+		}
+		other = otherTags(tn)
+	}
+	for _, typ := range csvTagColumns {
+		val := ""
+		if tn != nil {
+			val = strings.Join(tn.GetTags(typ), ";")
+		}
+		row = append(row, val)
+	}
+	codes := []string{}
+	for _, d := range rec.Diagnostics {
+		if d.Code != "" {
+			codes = append(codes, d.Code)
+		}
+	}
+	row = append(row, other, resolution, sar, strings.Join(codes, ";"), rec.Error)
+	return o.w.Write(row)
+}
+
+func (o *tCSVWriter) Flush() error {
+	o.w.Flush()
+	return o.w.Error()
+}
+
+// otherTags - tags without their own column as 'typ:val;typ:val'
+func otherTags(tn *tagname.TTagname) string {
+	skip := map[string]bool{}
+	for _, typ := range csvTagColumns {
+		skip[typ] = true
+	}
+	types := tn.ListTags()
+	sort.Strings(types)
+	ret := []string{}
+	for _, typ := range types {
+		if skip[typ] {
+			continue
+		}
+		for _, val := range tn.GetTags(typ) {
+			ret = append(ret, typ+":"+val)
+		}
+	}
+	return strings.Join(ret, ";")
+}
+
+var recordWriter tRecordWriter
+
+// writeRecord - converts and renames files of the job and writes the result instead of logging it
+func writeRecord(job *tJob, schema string) {
+	rec := &tRecord{Input: job.path, Targets: []string{}, Diagnostics: tagname.TDiagnostics{}, Tagnames: []*tagname.TTagname{}}
+	for _, tn := range job.list {
+		if tn != nil {
+			rec.Tagnames = append(rec.Tagnames, tn)
+		}
+	}
+	err := job.err
+	if err == nil {
+		for _, tn := range rec.Tagnames {
+			newPath, e := tn.ConvertTo(schema)
+			if e != nil {
+				err = fmt.Errorf("cannot convert to '%v': %v", schema, e)
+				break
+			}
+			rec.Targets = append(rec.Targets, newPath)
+		}
+	}
+	if err != nil {
+		rec.Error = err.Error()
+		rec.Diagnostics = tagname.Diagnostics(err)
+	}
+	rec.OK = err == nil
+	if flagReport {
+		globalTags.Add(job.list)
+	}
+	if err == nil && flagDoRename {
+		// the record is written after the plan is applied
+		planned := &tPlannedRecord{rec: rec}
+		for i, tn := range rec.Tagnames {
+			planned.items = append(planned.items, planRename(tn, rec.Targets[i], schema))
+		}
+		plannedRecords = append(plannedRecords, planned)
+		return
+	}
+	if flagDoRename {
+		plannedRecords = append(plannedRecords, &tPlannedRecord{rec: rec})
+		return
+	}
+	if e := recordWriter.Write(rec); e != nil {
+		log.Error(e)
+	}
+}
+
+// tPlannedRecord - a record and indices of its renames in the plan
+type tPlannedRecord struct {
+	rec   *tRecord
+	items []int
+}
+
+var plannedRecords []*tPlannedRecord
+
+// writePlannedRecords - writes records with the result of the applied plan
+func writePlannedRecords(applyErr error) {
+	conflicts := map[int][]string{}
+	for _, c := range renamePlan.Errors(flagResolveCycles) {
+		for _, i := range c.Items {
+			conflicts[i] = append(conflicts[i], c.Message)
+		}
+	}
+	for _, planned := range plannedRecords {
+		rec := planned.rec
+		if rec.OK {
+			list := []string{}
+			for _, i := range planned.items {
+				list = append(list, conflicts[i]...)
+			}
+			switch {
+			case len(list) > 0:
+				rec.OK = false
+				rec.Error = strings.Join(list, "\n")
+			case applyErr != nil:
+				rec.Error = applyErr.Error()
+			default:
+				rec.Renamed = true
+			}
+		}
+		if e := recordWriter.Write(rec); e != nil {
+			log.Error(e)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+
+	"github.com/macroblock/imed/pkg/tagname"
+)
+
+func TestCSVWriter(t *testing.T) {
+	table := []struct {
+		input string
+		want  map[string]string
+	}{
+		{"the_name_2018__hd_ar6e2.mp4", map[string]string{"name": "the_name", "sxx": "", "exx": "", "year": "2018", "other_tags": ""}},
+		{"the_show_s01_02-03_2019__hd_ar2.mp4", map[string]string{"name": "the_show", "sxx": "s01", "exx": "02-03", "year": "2019", "other_tags": ""}},
+	}
+	buf := &bytes.Buffer{}
+	w, err := newRecordWriter("csv", buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range table {
+		tn, err := tagname.NewFromString("", v.input, false)
+		if err != nil {
+			t.Fatalf("%q: NewFromString() error: %v", v.input, err)
+		}
+		rec := &tRecord{Input: v.input, OK: true, Tagnames: []*tagname.TTagname{tn}}
+		if err := w.Write(rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != len(table)+1 {
+		t.Fatalf("have %v rows, want %v", len(rows), len(table)+1)
+	}
+	column := map[string]int{}
+	for i, name := range rows[0] {
+		column[name] = i
+	}
+	for i, v := range table {
+		for name, want := range v.want {
+			j, ok := column[name]
+			if !ok {
+				t.Fatalf("no column %q", name)
+			}
+			if have := rows[i+1][j]; have != want {
+				t.Errorf("%q: %v = %q, want %q", v.input, name, have, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/macroblock/imed/pkg/renplan"
+	"github.com/macroblock/imed/pkg/tagname"
+	"github.com/macroblock/imed/pkg/watch"
+)
+
+// subfolders of the watched directory for processed files
+const (
+	watchDoneDir   = "done"
+	watchFailedDir = "failed"
+	// an error report is written next to a failed file with this suffix
+	watchReportExt = ".error.txt"
+)
+
+// watchDir - polls the directory tree and processes every file that has stopped growing.
+// A file is moved to 'done/' (renamed if -n is set) or to 'failed/' with an error report.
+func watchDir(root string, schema string, script *tagname.TScript) error {
+	fi, err := os.Stat(root)
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		return fmt.Errorf("%v is not a directory", root)
+	}
+	if flagWatchInterval <= 0 || flagWatchSettle < 0 {
+		return fmt.Errorf("invalid watch interval %v or settle time %v", flagWatchInterval, flagWatchSettle)
+	}
+	w := watch.New(root, time.Duration(flagWatchSettle)*time.Second)
+	w.Skip(func(rel string, fi os.FileInfo) bool {
+		if fi.IsDir() {
+			return rel == watchDoneDir || rel == watchFailedDir
+		}
+		return strings.HasSuffix(rel, watchReportExt)
+	})
+	log.Notice("watching ", root, " (interval ", flagWatchInterval, "s, settle ", flagWatchSettle, "s)")
+	log.Notice("journal: ", renameJournal.Path())
+	for {
+		paths, err := w.Poll()
+		if err != nil {
+			// a share may be temporarily unavailable
+			log.Warning(true, "cannot scan ", root, ": ", err)
+		}
+		for _, path := range paths {
+			processWatched(root, path, schema, script)
+		}
+		time.Sleep(time.Duration(flagWatchInterval) * time.Second)
+	}
+}
+
+// processWatched - checks, renames and moves the file to the done or failed folder
+func processWatched(root, path, schema string, script *tagname.TScript) {
+	log.Info("")
+	log.Info("watch: " + path)
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	job := prepare(path, schema, flagDeep, script)
+	if flagDiag {
+		printDiagnostics(path, job.err)
+	}
+	err = job.err
+	if err == nil {
+		err = moveDone(root, rel, job, schema)
+	}
+	if err == nil {
+		return
+	}
+	log.Warning(true, path, ": ", err)
+	failed := filepath.Join(root, watchFailedDir, rel)
+	if e := moveWatched(path, failed, schema); e != nil {
+		log.Error(e, "cannot move ", path, " to ", failed)
+		return
+	}
+	if e := writeWatchReport(failed, path, err); e != nil {
+		log.Error(e, "cannot write the error report of ", failed)
+	}
+	log.Notice("failed > ", failed)
+}
+
+// moveDone - moves the files of the job to the done folder keeping their relative directory
+func moveDone(root, rel string, job *tJob, schema string) error {
+	dir := filepath.Join(root, watchDoneDir, filepath.Dir(rel))
+	plan := renplan.New()
+	for _, tn := range job.list {
+		if tn == nil {
+			continue
+		}
+		newPath := tn.Source()
+		if flagDoRename {
+			p, err := tn.ConvertTo(schema)
+			if err != nil {
+				return fmt.Errorf("cannot convert to '%v': %v", schema, err)
+			}
+			newPath = p
+		}
+		s := schema
+		if s == "" {
+			s = tn.Schema()
+		}
+		plan.Add(tn.Source(), filepath.Join(dir, filepath.Base(newPath)), s)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := plan.Apply(false, renameJournal.Rename); err != nil {
+		return err
+	}
+	for _, item := range plan.Items {
+		log.Notice("done > ", item.Target)
+	}
+	return nil
+}
+
+// moveWatched - moves the file creating the directory of the target
+func moveWatched(src, dst, schema string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	if _, err := os.Lstat(dst); err == nil {
+		return fmt.Errorf("target %v already exists", dst)
+	}
+	return renameJournal.Rename(src, dst, schema)
+}
+
+// writeWatchReport - writes the error of the file next to it
+func writeWatchReport(path, source string, err error) error {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "file:  %v\n", source)
+	fmt.Fprintf(b, "time:  %v\n", time.Now().Format(time.RFC3339))
+	fmt.Fprintf(b, "error: %v\n", err)
+	// a parse error has no code and is fully described by the error
+	if diags := tagname.Diagnostics(err).Exclude(""); len(diags) > 0 {
+		fmt.Fprintf(b, "diagnostics:\n")
+		for _, d := range diags {
+			fmt.Fprintf(b, "  %v\n", formatDiagnostic(filepath.Base(source), d))
+		}
+	}
+	return os.WriteFile(path+watchReportExt, []byte(b.String()), 0644)
+}
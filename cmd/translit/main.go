@@ -11,6 +11,7 @@ import (
 
 	"github.com/macroblock/imed/pkg/cli"
 	"github.com/macroblock/imed/pkg/misc"
+	"github.com/macroblock/imed/pkg/renplan"
 	"github.com/macroblock/imed/pkg/translit"
 	"github.com/macroblock/imed/pkg/zlog/loglevel"
 	"github.com/macroblock/imed/pkg/zlog/zlog"
@@ -26,6 +27,9 @@ var (
 	flagD         string
 	flagN         string
 	flagRA        string
+	flagCycles    bool
+
+	renamePlan = renplan.New()
 )
 
 func doProcess(path string) {
@@ -50,8 +54,7 @@ func doProcess(path string) {
 	name = strings.TrimSuffix(name, ext)
 	name, _ = translit.Do(name)
 	name = upper(name)
-	err = os.Rename(path, dir+name+ext)
-	retif.Error(err, "cannot rename file")
+	renamePlan.Add(path, dir+name+ext, "")
 
 	log.Notice("result: " + dir + name + ext)
 }
@@ -132,6 +135,10 @@ func mainFunc() error {
 	for _, path := range flagFiles {
 		doProcess(path)
 	}
+	err := renamePlan.Apply(flagCycles, func(src, dst, _ string) error {
+		return os.Rename(src, dst)
+	})
+	log.Error(err)
 	return nil
 }
 
@@ -163,6 +170,7 @@ func main() {
 		cli.Flag("-d -delimiter : delimiter to separate multiple files. CR by default.", &flagD),
 		cli.Flag("-n            : template to save the name through ${orig}, ${translit} (does not work with files) ", &flagN),
 		cli.Flag("-ra           : remove after TEXT", &flagRA),
+		cli.Flag("-resolve-cycles : rename files that swap names (a->b, b->a) through temporary names", &flagCycles),
 		cli.Flag(": files to be processed", &flagFiles),
 		cli.OnError("Run '!PROG! -h' for usage.\n"),
 	)
@@ -2,6 +2,7 @@ package cli
 
 import (
 	"fmt"
+	"strconv"
 )
 
 // Do -
@@ -132,6 +133,16 @@ func getFunc(key string, variable interface{}) (int, func(string) error, error)
 		fn = func(val string) error {
 			return t()
 		}
+	case *int:
+		n = 1
+		fn = func(val string) error {
+			v, err := strconv.Atoi(val)
+			if err != nil {
+				return fmt.Errorf("a key %q requires an integer parameter, got %q", key, val)
+			}
+			*t = v
+			return nil
+		}
 	case *string:
 		n = 1
 		fn = func(val string) error {
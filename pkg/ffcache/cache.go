@@ -0,0 +1,319 @@
+package ffcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/malashin/ffinfo"
+)
+
+// EnvCacheDir - a cache directory or "off" to disable the cache
+const EnvCacheDir = "IMED_FFPROBE_CACHE"
+
+// DefaultMaxSize - the cache is trimmed to this size (in bytes) of entries
+const DefaultMaxSize = 256 << 20
+
+var (
+	mtx       sync.Mutex
+	cacheDir  string
+	isInited  bool
+	isEnabled = true
+	maxSize   = int64(DefaultMaxSize)
+	totalSize = int64(-1) // an estimate of the size of the entries, -1 until the directory is scanned
+
+	// probeFunc is replaced in tests
+	probeFunc = probe
+)
+
+// probe - ffinfo.Probe returns ffprobe's stderr as the error, which is empty if ffprobe cannot be run
+func probe(path string) (*ffinfo.File, error) {
+	info, err := ffinfo.Probe(path)
+	if err != nil && strings.TrimSpace(err.Error()) == "" {
+		return nil, fmt.Errorf("ffprobe failed on %v (is ffprobe in PATH?)", path)
+	}
+	return info, err
+}
+
+type tEntry struct {
+	Path    string       `json:"path"`
+	Size    int64        `json:"size"`
+	ModTime int64        `json:"mtime"`
+	Info    *ffinfo.File `json:"info"`
+}
+
+func initDir() {
+	if isInited {
+		return
+	}
+	isInited = true
+	dir := os.Getenv(EnvCacheDir)
+	switch strings.ToLower(dir) {
+	case "off", "none", "0":
+		isEnabled = false
+		return
+	case "":
+		base, err := os.UserCacheDir()
+		if err != nil {
+			isEnabled = false
+			return
+		}
+		dir = filepath.Join(base, "imed", "ffprobe")
+	}
+	cacheDir = dir
+}
+
+// SetDir - sets the cache directory. An empty string disables the cache.
+func SetDir(dir string) {
+	mtx.Lock()
+	defer mtx.Unlock()
+	isInited = true
+	cacheDir = dir
+	isEnabled = dir != ""
+	totalSize = -1
+}
+
+// Dir - returns the cache directory or an empty string if the cache is disabled
+func Dir() string {
+	mtx.Lock()
+	defer mtx.Unlock()
+	initDir()
+	if !isEnabled {
+		return ""
+	}
+	return cacheDir
+}
+
+// SetMaxSize - sets the max total size of the cache entries in bytes
+func SetMaxSize(size int64) {
+	mtx.Lock()
+	defer mtx.Unlock()
+	maxSize = size
+}
+
+// reEntryName - names of the files the cache writes: entries and temporary files of a store
+var reEntryName = regexp.MustCompile(`^([0-9a-f]{64}\.json|tmp-.*)$`)
+
+func entryPath(absPath string) string {
+	sum := sha256.Sum256([]byte(absPath))
+	return filepath.Join(cacheDir, hex.EncodeToString(sum[:])+".json")
+}
+
+// Probe - returns the ffprobe result of the file. The result is read from the cache
+// if the file has the same size and modification time as the cached one.
+func Probe(path string) (*ffinfo.File, error) {
+	dir := Dir()
+	if dir == "" {
+		return probeFunc(path)
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return probeFunc(path)
+	}
+	stat, err := os.Stat(absPath)
+	if err != nil {
+		return nil, err
+	}
+
+	mtx.Lock()
+	info := load(absPath, stat)
+	mtx.Unlock()
+	if info != nil {
+		return info, nil
+	}
+
+	info, err = probeFunc(path)
+	if err != nil {
+		return nil, err
+	}
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	err = store(absPath, stat, info)
+	if err != nil {
+		// the cache is an optimization only
+		return info, nil
+	}
+	if totalSize < 0 || totalSize > maxSize {
+		trim()
+	}
+	return info, nil
+}
+
+func load(absPath string, stat os.FileInfo) *ffinfo.File {
+	name := entryPath(absPath)
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil
+	}
+	entry := tEntry{}
+	err = json.Unmarshal(data, &entry)
+	if err != nil || entry.Info == nil || entry.Path != absPath {
+		removeEntry(name)
+		return nil
+	}
+	if entry.Size != stat.Size() || entry.ModTime != stat.ModTime().UnixNano() {
+		// the file has been changed
+		removeEntry(name)
+		return nil
+	}
+	now := time.Now()
+	_ = os.Chtimes(name, now, now)
+	return entry.Info
+}
+
+func store(absPath string, stat os.FileInfo, info *ffinfo.File) error {
+	err := os.MkdirAll(cacheDir, 0755)
+	if err != nil {
+		return err
+	}
+	entry := tEntry{Path: absPath, Size: stat.Size(), ModTime: stat.ModTime().UnixNano(), Info: info}
+	data, err := json.Marshal(&entry)
+	if err != nil {
+		return err
+	}
+	name := entryPath(absPath)
+	tmp, err := os.CreateTemp(cacheDir, "tmp-*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if err1 := tmp.Close(); err == nil {
+		err = err1
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	oldSize := int64(0)
+	if fi, err := os.Stat(name); err == nil {
+		oldSize = fi.Size()
+	}
+	err = os.Rename(tmp.Name(), name)
+	if err == nil && totalSize >= 0 {
+		totalSize += int64(len(data)) - oldSize
+	}
+	return err
+}
+
+// removeEntry - removes the entry file and keeps the size estimate
+func removeEntry(name string) error {
+	fi, err := os.Stat(name)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(name)
+	if err == nil && totalSize >= 0 {
+		totalSize -= fi.Size()
+	}
+	return err
+}
+
+// trim - removes the least recently used entries while the cache is bigger than maxSize.
+// It scans the directory, so it is called only when the size estimate is unknown or exceeds
+// maxSize, and it resets the estimate to the scanned size.
+func trim() {
+	if maxSize <= 0 {
+		return
+	}
+	list, err := os.ReadDir(cacheDir)
+	if err != nil {
+		return
+	}
+	type tItem struct {
+		name string
+		size int64
+		time time.Time
+	}
+	items := []tItem{}
+	total := int64(0)
+	for _, e := range list {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		fi, err := e.Info()
+		if err != nil {
+			continue
+		}
+		items = append(items, tItem{e.Name(), fi.Size(), fi.ModTime()})
+		total += fi.Size()
+	}
+	totalSize = total
+	if total <= maxSize {
+		return
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].time.Before(items[j].time) })
+	// leave some space to avoid trimming on every store
+	limit := maxSize * 3 / 4
+	for _, item := range items {
+		if total <= limit {
+			break
+		}
+		if os.Remove(filepath.Join(cacheDir, item.name)) == nil {
+			total -= item.size
+		}
+	}
+	totalSize = total
+}
+
+// Invalidate - removes the cache entry of the file
+func Invalidate(path string) error {
+	dir := Dir()
+	if dir == "" {
+		return nil
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	mtx.Lock()
+	defer mtx.Unlock()
+	err = removeEntry(entryPath(absPath))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// Clear - removes all the cache entries. It refuses to clear a directory that holds
+// files the cache has not written, as the directory is set by the user.
+func Clear() error {
+	dir := Dir()
+	if dir == "" {
+		return nil
+	}
+	mtx.Lock()
+	defer mtx.Unlock()
+	list, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, e := range list {
+		if e.IsDir() || !reEntryName.MatchString(e.Name()) {
+			return fmt.Errorf("cannot clear the cache: %v is not a cache directory (it contains %q)", dir, e.Name())
+		}
+	}
+	errors := []string{}
+	for _, e := range list {
+		err := os.Remove(filepath.Join(dir, e.Name()))
+		if err != nil {
+			errors = append(errors, err.Error())
+		}
+	}
+	totalSize = -1
+	if len(errors) > 0 {
+		return fmt.Errorf("cannot clear the cache:\n  %v", strings.Join(errors, "\n  "))
+	}
+	return nil
+}
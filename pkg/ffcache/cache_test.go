@@ -0,0 +1,155 @@
+package ffcache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/malashin/ffinfo"
+)
+
+func TestProbe(t *testing.T) {
+	dir := t.TempDir()
+	SetDir(filepath.Join(dir, "cache"))
+	defer SetDir("")
+
+	calls := 0
+	probeFunc = func(path string) (*ffinfo.File, error) {
+		calls++
+		info := &ffinfo.File{}
+		info.Format.FormatName = "mov,mp4,m4a,3gp,3g2,mj2"
+		return info, nil
+	}
+	defer func() { probeFunc = probe }()
+
+	path := filepath.Join(dir, "a.mp4")
+	if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, want := range []int{1, 1} {
+		info, err := Probe(path)
+		if err != nil {
+			t.Fatalf("#%v: unexpected error: %v", i, err)
+		}
+		if info.Format.FormatName != "mov,mp4,m4a,3gp,3g2,mj2" {
+			t.Errorf("#%v: unexpected format %q", i, info.Format.FormatName)
+		}
+		if calls != want {
+			t.Errorf("#%v: ffprobe calls %v, want %v", i, calls, want)
+		}
+	}
+
+	// a changed file must be probed again
+	if err := os.WriteFile(path, []byte("other data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Probe(path); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Errorf("changed file: ffprobe calls %v, want 2", calls)
+	}
+
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Probe(path); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 {
+		t.Errorf("touched file: ffprobe calls %v, want 3", calls)
+	}
+
+	if err := Invalidate(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Probe(path); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 4 {
+		t.Errorf("invalidated entry: ffprobe calls %v, want 4", calls)
+	}
+}
+
+func TestTrim(t *testing.T) {
+	dir := t.TempDir()
+	SetDir(filepath.Join(dir, "cache"))
+	defer SetDir("")
+	defer SetMaxSize(DefaultMaxSize)
+
+	probeFunc = func(path string) (*ffinfo.File, error) { return &ffinfo.File{}, nil }
+	defer func() { probeFunc = probe }()
+
+	entrySize := int64(0)
+	for i, name := range []string{"a", "b", "c", "d"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Probe(path); err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			list, _ := os.ReadDir(Dir())
+			fi, _ := list[0].Info()
+			entrySize = fi.Size()
+			SetMaxSize(entrySize * 3)
+		}
+		old := time.Now().Add(time.Duration(i-10) * time.Minute)
+		abs, _ := filepath.Abs(path)
+		_ = os.Chtimes(entryPath(abs), old, old)
+	}
+	list, err := os.ReadDir(Dir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) > 3 {
+		t.Errorf("cache was not trimmed: %v entries", len(list))
+	}
+	abs, _ := filepath.Abs(filepath.Join(dir, "d"))
+	if _, err := os.Stat(entryPath(abs)); err != nil {
+		t.Errorf("the most recent entry was removed: %v", err)
+	}
+}
+
+func TestClear(t *testing.T) {
+	dir := t.TempDir()
+	SetDir(filepath.Join(dir, "cache"))
+	defer SetDir("")
+
+	probeFunc = func(path string) (*ffinfo.File, error) { return &ffinfo.File{}, nil }
+	defer func() { probeFunc = probe }()
+
+	path := filepath.Join(dir, "a.mp4")
+	if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Probe(path); err != nil {
+		t.Fatal(err)
+	}
+
+	// a directory with other files must be left intact
+	other := filepath.Join(Dir(), "notes.txt")
+	if err := os.WriteFile(other, []byte("keep"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Clear(); err == nil {
+		t.Errorf("Clear() of a directory with other files: error expected")
+	}
+	if list, _ := os.ReadDir(Dir()); len(list) != 2 {
+		t.Errorf("Clear() removed files of a directory with other files: %v left", len(list))
+	}
+
+	if err := os.Remove(other); err != nil {
+		t.Fatal(err)
+	}
+	if err := Clear(); err != nil {
+		t.Fatalf("Clear() error: %v", err)
+	}
+	if list, _ := os.ReadDir(Dir()); len(list) != 0 {
+		t.Errorf("Clear() left %v entries", len(list))
+	}
+}
@@ -0,0 +1,245 @@
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EnvJournalDir - a directory for the journals of DefaultPath
+const EnvJournalDir = "IMED_JOURNAL_DIR"
+
+// TEntry - a record of one applied rename. Size and ModTime describe the file after the rename.
+type TEntry struct {
+	Source  string    `json:"source"`
+	Target  string    `json:"target"`
+	Time    time.Time `json:"time"`
+	Tool    string    `json:"tool"`
+	Schema  string    `json:"schema,omitempty"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mtime"`
+}
+
+// TJournal - an append only list of renames (one JSON object per line)
+type TJournal struct {
+	mtx  sync.Mutex
+	path string
+	tool string
+	file *os.File
+}
+
+// DefaultPath - returns a new journal path for the tool in the journal directory
+func DefaultPath(tool string) (string, error) {
+	dir := os.Getenv(EnvJournalDir)
+	if dir == "" {
+		base, err := os.UserCacheDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(base, "imed", "journal")
+	}
+	name := fmt.Sprintf("%v-%v.journal", tool, time.Now().Format("20060102-150405.000"))
+	return filepath.Join(dir, name), nil
+}
+
+// Open - opens the journal for appending. The file is created on the first rename.
+func Open(path, tool string) *TJournal {
+	return &TJournal{path: path, tool: tool}
+}
+
+// Path -
+func (o *TJournal) Path() string {
+	return o.path
+}
+
+// IsEmpty - true if nothing has been written to the journal
+func (o *TJournal) IsEmpty() bool {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	return o.file == nil
+}
+
+// Rename - records the rename to the journal and renames the file. The entry is written
+// and synced first, so a rename the journal does not record never happens. The entry is
+// removed if the rename fails.
+func (o *TJournal) Rename(src, dst, schema string) error {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	absSrc, err := filepath.Abs(src)
+	if err != nil {
+		return err
+	}
+	absDst, err := filepath.Abs(dst)
+	if err != nil {
+		return err
+	}
+	if absSrc == absDst {
+		return nil
+	}
+	// a rename keeps the size and the modification time of the file
+	fi, err := os.Stat(absSrc)
+	if err != nil {
+		return err
+	}
+	entry := &TEntry{Source: absSrc, Target: absDst, Time: time.Now(), Tool: o.tool, Schema: schema,
+		Size: fi.Size(), ModTime: fi.ModTime()}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	if o.file == nil {
+		err := os.MkdirAll(filepath.Dir(o.path), 0755)
+		if err != nil {
+			return err
+		}
+		o.file, err = os.OpenFile(o.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+	}
+	jfi, err := o.file.Stat()
+	if err != nil {
+		return err
+	}
+	_, err = o.file.Write(append(data, '\n'))
+	if err == nil {
+		err = o.file.Sync()
+	}
+	if err != nil {
+		o.discard(jfi.Size())
+		return fmt.Errorf("file %v was not renamed, the journal cannot be written: %v", src, err)
+	}
+
+	err = os.Rename(src, dst)
+	if err != nil {
+		if e := o.discard(jfi.Size()); e != nil {
+			return fmt.Errorf("%v (the journal keeps the entry of the failed rename: %v)", err, e)
+		}
+		return err
+	}
+	return nil
+}
+
+// discard - removes the entries written after the size, an empty journal file is removed
+func (o *TJournal) discard(size int64) error {
+	err := o.file.Truncate(size)
+	if err != nil || size > 0 {
+		return err
+	}
+	o.file.Close()
+	o.file = nil
+	return os.Remove(o.path)
+}
+
+// Close -
+func (o *TJournal) Close() error {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	if o.file == nil {
+		return nil
+	}
+	err := o.file.Close()
+	o.file = nil
+	return err
+}
+
+// Load - reads entries of the journal
+func Load(path string) ([]*TEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	ret := []*TEntry{}
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		entry := &TEntry{}
+		err := json.Unmarshal([]byte(line), entry)
+		if err != nil {
+			return nil, fmt.Errorf("%v:%v: %v", path, lineNo, err)
+		}
+		if entry.Source == "" || entry.Target == "" {
+			return nil, fmt.Errorf("%v:%v: source or target is empty", path, lineNo)
+		}
+		ret = append(ret, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// isSameName - a case only rename on a case insensitive file system
+func isSameName(a, b string) bool {
+	return a != b && strings.EqualFold(a, b)
+}
+
+// CheckUndo - checks that the renames of the journal can be reverted. It returns a list of
+// problems: a target is absent, modified or replaced, or a source path is occupied.
+func CheckUndo(entries []*TEntry) []error {
+	errs := []error{}
+	restored := map[string]bool{} // paths that are restored by the later entries
+	freed := map[string]bool{}    // paths that are freed by the later entries
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		if !restored[e.Target] {
+			fi, err := os.Stat(e.Target)
+			switch {
+			case err != nil:
+				errs = append(errs, fmt.Errorf("target %v: %v", e.Target, err))
+			case fi.Size() != e.Size || !fi.ModTime().Equal(e.ModTime):
+				errs = append(errs, fmt.Errorf("target %v has been modified or replaced since %v", e.Target, e.Time.Format(time.RFC3339)))
+			}
+		}
+		if !freed[e.Source] && !isSameName(e.Source, e.Target) {
+			if _, err := os.Lstat(e.Source); err == nil {
+				errs = append(errs, fmt.Errorf("source %v already exists", e.Source))
+			}
+		}
+		delete(restored, e.Target)
+		delete(freed, e.Source)
+		freed[e.Target] = true
+		restored[e.Source] = true
+	}
+	return errs
+}
+
+// Undo - reverts the renames of the journal in reverse order. Nothing is renamed if
+// any of the renames cannot be reverted safely.
+func Undo(path string, fn func(e *TEntry)) error {
+	entries, err := Load(path)
+	if err != nil {
+		return err
+	}
+	if errs := CheckUndo(entries); len(errs) > 0 {
+		list := []string{}
+		for _, err := range errs {
+			list = append(list, err.Error())
+		}
+		return fmt.Errorf("cannot undo %v:\n  %v", path, strings.Join(list, "\n  "))
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		err := os.Rename(e.Target, e.Source)
+		if err != nil {
+			return fmt.Errorf("undo stopped at %v -> %v: %v", e.Target, e.Source, err)
+		}
+		if fn != nil {
+			fn(e)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package journal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUndo(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.mp4")
+	b := filepath.Join(dir, "b.mp4")
+	c := filepath.Join(dir, "c.mp4")
+	d := filepath.Join(dir, "d.mp4")
+	writeFile(t, a, "aaa")
+	writeFile(t, c, "ccc")
+
+	path := filepath.Join(dir, "j", "test.journal")
+	j := Open(path, "test")
+	for _, v := range [][2]string{{a, b}, {b, d}, {c, a}, {d, d}} {
+		if err := j.Rename(v[0], v[1], "rt"); err != nil {
+			t.Fatalf("Rename(%v, %v) error: %v", v[0], v[1], err)
+		}
+	}
+	if err := j.Close(); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 || entries[0].Tool != "test" || entries[0].Schema != "rt" || entries[0].Size != 3 {
+		t.Fatalf("unexpected entries: %+v", entries)
+	}
+
+	n := 0
+	if err := Undo(path, func(*TEntry) { n++ }); err != nil {
+		t.Fatalf("Undo() error: %v", err)
+	}
+	if n != 3 {
+		t.Errorf("undone %v renames, want 3", n)
+	}
+	for name, want := range map[string]string{a: "aaa", c: "ccc"} {
+		data, err := os.ReadFile(name)
+		if err != nil || string(data) != want {
+			t.Errorf("%v: have %q (%v), want %q", name, data, err, want)
+		}
+	}
+	for _, name := range []string{b, d} {
+		if _, err := os.Stat(name); err == nil {
+			t.Errorf("%v still exists", name)
+		}
+	}
+}
+
+func TestRenameNotRecorded(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.mp4")
+	b := filepath.Join(dir, "b.mp4")
+	writeFile(t, a, "aaa")
+
+	path := filepath.Join(dir, "test.journal")
+	j := Open(path, "test")
+	defer j.Close()
+	// a no-op does not create the journal
+	if err := j.Rename(a, a, "rt"); err != nil {
+		t.Fatalf("Rename() of a no-op error: %v", err)
+	}
+	// a failed rename leaves no entry
+	if err := j.Rename(a, filepath.Join(dir, "absent", "b.mp4"), "rt"); err == nil {
+		t.Fatalf("Rename() into an absent directory: error expected")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) || !j.IsEmpty() {
+		t.Errorf("the journal exists after a no-op and a failed rename: %v", err)
+	}
+
+	if err := j.Rename(a, b, "rt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Rename(b, filepath.Join(dir, "absent", "a.mp4"), "rt"); err == nil {
+		t.Fatalf("Rename() into an absent directory: error expected")
+	}
+	entries, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Target != b {
+		t.Errorf("unexpected entries: %+v", entries)
+	}
+}
+
+func TestUndoRefuses(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.mp4")
+	b := filepath.Join(dir, "b.mp4")
+	c := filepath.Join(dir, "c.mp4")
+	e := filepath.Join(dir, "e.mp4")
+	writeFile(t, a, "aaa")
+	writeFile(t, c, "ccc")
+
+	path := filepath.Join(dir, "test.journal")
+	j := Open(path, "test")
+	if err := j.Rename(a, b, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Rename(c, e, ""); err != nil {
+		t.Fatal(err)
+	}
+	j.Close()
+
+	// the target was modified
+	writeFile(t, b, "modified")
+	later := time.Now().Add(time.Hour)
+	os.Chtimes(b, later, later)
+	// the source is occupied
+	writeFile(t, c, "new")
+
+	err := Undo(path, nil)
+	if err == nil {
+		t.Fatalf("Undo() has no error")
+	}
+	if len(CheckUndo(mustLoad(t, path))) != 2 {
+		t.Errorf("want 2 problems, have %v", err)
+	}
+	// nothing is renamed
+	for _, name := range []string{b, c, e} {
+		if _, err := os.Stat(name); err != nil {
+			t.Errorf("%v: %v", name, err)
+		}
+	}
+}
+
+func mustLoad(t *testing.T, path string) []*TEntry {
+	t.Helper()
+	ret, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ret
+}
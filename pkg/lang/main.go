@@ -17,34 +17,30 @@ var (
 	byCode    map[string]*Record
 )
 
-func ByName(s string) *Record {
-	if byName == nil {
-		byName = map[string]*Record{}
-		for i := range table {
-			if table[i].Code >= 0 {
-				byName[table[i].Name] = &table[i]
-			}
+// init - the maps are built once, as lookups may run concurrently
+func init() {
+	byName = map[string]*Record{}
+	byLat = map[string]*Record{}
+	for i := range table {
+		if table[i].Code >= 0 {
+			byName[table[i].Name] = &table[i]
+			byLat[table[i].Lat] = &table[i]
 		}
-		for i := range table {
-			if table[i].Code < 0 {
-				if rec, ok := byName[table[i].Comment]; ok {
-					byName[table[i].Name] = rec
-				}
+	}
+	for i := range table {
+		if table[i].Code < 0 {
+			if rec, ok := byName[table[i].Comment]; ok {
+				byName[table[i].Name] = rec
 			}
 		}
 	}
+}
+
+func ByName(s string) *Record {
 	return byName[s]
 }
 
 func ByLat(s string) *Record {
-	if byLat == nil {
-		byLat = map[string]*Record{}
-		for i := range table {
-			if table[i].Code >= 0 {
-				byLat[table[i].Lat] = &table[i]
-			}
-		}
-	}
 	return byLat[s]
 }
 
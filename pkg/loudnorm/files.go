@@ -5,8 +5,8 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/macroblock/imed/pkg/ffcache"
 	"github.com/macroblock/imed/pkg/ffmpeg"
-	"github.com/malashin/ffinfo"
 )
 
 type (
@@ -69,7 +69,7 @@ func (o *TFileInfo) String() string {
 
 // LoadFile -
 func LoadFile(filename string, inputIndex int) (*TFileInfo, error) {
-	finfo, err := ffinfo.Probe(filename)
+	finfo, err := ffcache.Probe(filename)
 	if err != nil {
 		return nil, err
 	}
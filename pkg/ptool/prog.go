@@ -15,6 +15,7 @@ type TParser struct {
 	code    []TInstruction
 	items   []tProgItem
 	entries []int
+	steps   int
 }
 
 // TInstruction -
@@ -42,11 +43,51 @@ type TPos struct {
 	w         int
 }
 
+// Offset - byte offset of the rune at the position
+func (o TPos) Offset() int {
+	return int(o.offs) - o.w
+}
+
+// Line -
+func (o TPos) Line() int {
+	return o.line
+}
+
+// Col -
+func (o TPos) Col() int {
+	return o.col
+}
+
+// Rune - the rune at the position, RuneEOF at the end of the source
+func (o TPos) Rune() rune {
+	return o.r
+}
+
 // String -
 func (o TPos) String() string {
 	return fmt.Sprintf("[0x%04x] (%v,%v)", o.offs, o.line+1, o.col)
 }
 
+// TParseError - a failure at the farthest position the parser has reached
+type TParseError struct {
+	Pos        TPos
+	Unexpected bool     // a negative lookahead has matched at the position
+	Expected   []string // rules that have failed at the position, the innermost first
+}
+
+// Error -
+func (o *TParseError) Error() string {
+	prefix := "expected"
+	if o.Unexpected {
+		prefix = "unexpected"
+	}
+	str := "end of file"
+	if o.Pos.r != RuneEOF {
+		str = fmt.Sprintf("%q", o.Pos.r)
+	}
+	return fmt.Sprintf("[%06x] (l:%v, c:%v): %v %v", o.Pos.offs, o.Pos.line, o.Pos.col, prefix, str)
+}
+
 // RuneEOF -
 const RuneEOF = rune(0x7fffffff) //rune(^0) //'\U0010ffff'
 
@@ -78,8 +119,6 @@ const (
 	opMAXINSTRUCTION
 )
 
-var stepCounter = 0
-
 // newParser -
 func newParser() *TParser {
 	return &TParser{}
@@ -156,13 +195,19 @@ func (o *TParser) Parse(src string, entry ...string) (*TNode, error) {
 	}
 
 	o.src = bytes.NewReader([]byte(src))
-	stepCounter = 0
+	o.steps = 0
 	type tfs struct {
 		pos TPos
 		r   rune
 	}
 	expected := false
 	errpos := TPos{}
+	errRules := []string{}
+	names := map[TOffset]string{}
+	for _, item := range o.items {
+		names[item.ip] = item.name
+	}
+	cs := []TOffset{} // called rules
 	// fmt.Println("start")
 	ps := []TOffset{}
 	fs := []TPos{}
@@ -194,15 +239,7 @@ func (o *TParser) Parse(src string, entry ...string) (*TNode, error) {
 			// fmt.Printf("ps: %v fs: %v ls: %v ns %v\n", len(ps), len(fs), len(ls), len(ns))
 			// res = instr.data.(bool)
 			if !res {
-				prefix := "expected"
-				if expected {
-					prefix = "unexpected"
-				}
-				str := "end of file"
-				if errpos.r != RuneEOF {
-					str = fmt.Sprintf("%q", errpos.r)
-				}
-				return tree, fmt.Errorf("[%06x] (l:%v, c:%v): %v %v", errpos.offs, errpos.line, errpos.col, prefix, str)
+				return tree, &TParseError{Pos: errpos, Unexpected: expected, Expected: errRules}
 			}
 			return tree, nil
 		case opJMP:
@@ -225,12 +262,14 @@ func (o *TParser) Parse(src string, entry ...string) (*TNode, error) {
 			o.log("call", ip, instr.data, "")
 			ps = append(ps, ip)
 			ip = instr.data.(TOffset)
+			cs = append(cs, ip)
 			ip--
 		case opRET:
 			o.log("ret", ip, instr.data, "")
 			res = instr.data.(bool)
 			ip = ps[len(ps)-1]
 			ps = ps[:len(ps)-1]
+			cs = cs[:len(cs)-1]
 		case opTRUE:
 			o.log("true", ip, "", "")
 			res = true
@@ -241,7 +280,13 @@ func (o *TParser) Parse(src string, entry ...string) (*TNode, error) {
 			o.log("seterror", ip, instr.data, "")
 			expected = instr.data.(bool)
 			if o.cpos.offs >= errpos.offs {
+				if o.cpos.offs > errpos.offs {
+					errRules = errRules[:0]
+				}
 				errpos = o.cpos
+				if len(cs) > 0 {
+					errRules = appendRule(errRules, names[cs[len(cs)-1]])
+				}
 			}
 		case opMARK:
 			o.log("mark", ip, "", "")
@@ -293,6 +338,7 @@ func (o *TParser) Parse(src string, entry ...string) (*TNode, error) {
 				}
 			}
 			x := cnode
+			x.Pos = pos
 			cnode = ns[len(ns)-1]
 			ns = ns[:len(ns)-1]
 			cnode.Links = append(cnode.Links, x)
@@ -327,12 +373,24 @@ func (o *TParser) Parse(src string, entry ...string) (*TNode, error) {
 	} // for
 }
 
+func appendRule(list []string, name string) []string {
+	if name == "" {
+		return list
+	}
+	for _, s := range list {
+		if s == name {
+			return list
+		}
+	}
+	return append(list, name)
+}
+
 func fmtError(err interface{}) error {
 	return fmt.Errorf("(internal) %v", err)
 }
 
 func (o *TParser) log(cmd string, ip TOffset, p1, p2 interface{}) {
-	stepCounter++
+	o.steps++
 	s := ""
 	switch p1.(type) {
 	default:
@@ -343,7 +401,7 @@ func (o *TParser) log(cmd string, ip TOffset, p1, p2 interface{}) {
 		s = fmt.Sprintf("%6v %-12v %q = %q", ip, cmd, p1, o.cpos.r)
 	}
 	_ = s
-	// fmt.Printf("%6v %v\n", stepCounter, s)
+	// fmt.Printf("%6v %v\n", o.steps, s)
 }
 
 // ByID -
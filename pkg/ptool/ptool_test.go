@@ -35,3 +35,28 @@ anyrune = \x00..$;
 	fmt.Println(TreeToString(tree, p.ByID))
 
 }
+
+// TestParseError -
+func TestParseError(t *testing.T) {
+	p, err := NewBuilder().FromString(`
+entry = @word ' ' @number $;
+word = letter {letter};
+number = digit {digit};
+letter = 'a'..'z';
+digit = '0'..'9';
+`).Entries("entry").Build()
+	if err != nil {
+		t.Fatalf("builder error: %v", err)
+	}
+	_, err = p.Parse("abc 12x")
+	pe, ok := err.(*TParseError)
+	if !ok {
+		t.Fatalf("want *TParseError, have %T: %v", err, err)
+	}
+	if pe.Pos.Offset() != 6 || pe.Pos.Rune() != 'x' {
+		t.Errorf("want offset 6 rune 'x', have %v %q", pe.Pos.Offset(), pe.Pos.Rune())
+	}
+	if len(pe.Expected) == 0 || pe.Expected[0] != "digit" {
+		t.Errorf("want 'digit' expected first, have %v", pe.Expected)
+	}
+}
@@ -0,0 +1,350 @@
+package renplan
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// conflict kinds
+const (
+	ConflictDuplicate = "duplicate_target" // several sources have the same target
+	ConflictCase      = "case_collision"   // targets differ only in case
+	ConflictExists    = "target_exists"    // the target exists and is not renamed by the plan
+	ConflictCycle     = "cycle"            // renames form a cycle (a->b, b->a)
+)
+
+// TItem - a single rename of the plan
+type TItem struct {
+	Source string
+	Target string
+	Schema string
+
+	absSource string
+	absTarget string
+}
+
+// IsNoop - the source and the target are the same path
+func (o *TItem) IsNoop() bool {
+	return o.absSource == o.absTarget
+}
+
+// TConflict -
+type TConflict struct {
+	Kind    string
+	Items   []int // indices of the items in the plan
+	Message string
+}
+
+// Error -
+func (o *TConflict) Error() string {
+	return o.Message
+}
+
+// TPlan - a batch of renames that are checked together before any of them is applied
+type TPlan struct {
+	Items     []*TItem
+	Conflicts []*TConflict
+	order     []int   // items in the order they can be applied in
+	cycles    [][]int // items of every cycle
+	isChecked bool
+}
+
+// New -
+func New() *TPlan {
+	return &TPlan{}
+}
+
+// Add - adds a rename to the plan and returns its index
+func (o *TPlan) Add(source, target, schema string) int {
+	o.isChecked = false
+	item := &TItem{Source: source, Target: target, Schema: schema}
+	item.absSource = absPath(source)
+	item.absTarget = absPath(target)
+	o.Items = append(o.Items, item)
+	return len(o.Items) - 1
+}
+
+func absPath(path string) string {
+	ret, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+	return ret
+}
+
+func lowerPath(path string) string {
+	return strings.ToLower(path)
+}
+
+func (o *TPlan) addConflict(kind string, items []int, format string, args ...interface{}) {
+	o.Conflicts = append(o.Conflicts, &TConflict{Kind: kind, Items: items, Message: fmt.Sprintf(format, args...)})
+}
+
+// Check - finds conflicts and cycles and computes the order of renames. It returns all
+// the conflicts. Cycles are reported as conflicts too, but they can be resolved by Apply.
+func (o *TPlan) Check() []*TConflict {
+	o.Conflicts = nil
+	o.order = nil
+	o.cycles = nil
+	o.isChecked = true
+
+	sources := map[string]int{}      // exact source path -> item
+	lowerSources := map[string]int{} // lower case source path -> item
+	for i, item := range o.Items {
+		if item.IsNoop() {
+			continue
+		}
+		sources[item.absSource] = i
+		lowerSources[lowerPath(item.absSource)] = i
+	}
+
+	// duplicate and case insensitive targets
+	byTarget := map[string][]int{}
+	targets := []string{}
+	for i, item := range o.Items {
+		if item.IsNoop() {
+			continue // a collision with it is found as an existing target
+		}
+		key := lowerPath(item.absTarget)
+		if _, ok := byTarget[key]; !ok {
+			targets = append(targets, key)
+		}
+		byTarget[key] = append(byTarget[key], i)
+	}
+	for _, key := range targets {
+		list := byTarget[key]
+		if len(list) < 2 {
+			continue
+		}
+		names := []string{}
+		isExact := true
+		for _, i := range list {
+			names = append(names, o.Items[i].Source)
+			if o.Items[i].absTarget != o.Items[list[0]].absTarget {
+				isExact = false
+			}
+		}
+		if isExact {
+			o.addConflict(ConflictDuplicate, list, "%v sources have the same target %v: %v",
+				len(list), o.Items[list[0]].Target, strings.Join(names, ", "))
+			continue
+		}
+		o.addConflict(ConflictCase, list, "targets differ only in case: %v", strings.Join(o.targetsOf(list), ", "))
+	}
+
+	// targets that exist on the disk
+	dirs := map[string][]string{}
+	for i, item := range o.Items {
+		if item.IsNoop() {
+			continue
+		}
+		if _, ok := sources[item.absTarget]; ok {
+			continue // freed by another rename
+		}
+		if fi, err := os.Lstat(item.absTarget); err == nil {
+			src, err := os.Lstat(item.absSource)
+			if err == nil && os.SameFile(fi, src) {
+				continue // a case only rename on a case insensitive file system
+			}
+			o.addConflict(ConflictExists, []int{i}, "target %v already exists (source %v)", item.Target, item.Source)
+			continue
+		}
+		// a file with the same name in another case
+		dir := filepath.Dir(item.absTarget)
+		names, ok := dirs[dir]
+		if !ok {
+			names = readDirNames(dir)
+			dirs[dir] = names
+		}
+		base := filepath.Base(item.absTarget)
+		for _, name := range names {
+			path := filepath.Join(dir, name)
+			if name == base || !strings.EqualFold(name, base) || path == item.absSource {
+				continue
+			}
+			if j, ok := lowerSources[lowerPath(path)]; ok && o.Items[j].absSource == path {
+				continue // renamed by the plan
+			}
+			o.addConflict(ConflictCase, []int{i}, "target %v differs only in case from existing %v", item.Target, path)
+		}
+	}
+
+	o.plan()
+	for _, cycle := range o.cycles {
+		o.addConflict(ConflictCycle, cycle, "renames form a cycle: %v", strings.Join(o.cycleNames(cycle), " -> "))
+	}
+	return o.Conflicts
+}
+
+func readDirNames(dir string) []string {
+	list, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	ret := []string{}
+	for _, e := range list {
+		ret = append(ret, e.Name())
+	}
+	return ret
+}
+
+func (o *TPlan) targetsOf(list []int) []string {
+	ret := []string{}
+	for _, i := range list {
+		ret = append(ret, o.Items[i].Target)
+	}
+	return ret
+}
+
+func (o *TPlan) cycleNames(cycle []int) []string {
+	ret := []string{}
+	for _, i := range cycle {
+		ret = append(ret, o.Items[i].Source)
+	}
+	return append(ret, o.Items[cycle[0]].Source)
+}
+
+// plan - orders renames so that a target is freed before it is used.
+// An item depends on the item whose source is its target.
+func (o *TPlan) plan() {
+	next := map[int]int{} // item -> the item that must be applied before it
+	sources := map[string]int{}
+	for i, item := range o.Items {
+		if !item.IsNoop() {
+			sources[item.absSource] = i
+		}
+	}
+	for i, item := range o.Items {
+		if item.IsNoop() {
+			continue
+		}
+		if j, ok := sources[item.absTarget]; ok && j != i {
+			next[i] = j
+		}
+	}
+	// every item has at most one dependency, so the graph is a set of chains and cycles
+	state := map[int]int{} // 0 - new, 1 - in progress, 2 - done
+	var visit func(i int, path []int)
+	visit = func(i int, path []int) {
+		switch state[i] {
+		case 2:
+			return
+		case 1:
+			// a cycle: the part of the path starting at i
+			for k, j := range path {
+				if j == i {
+					cycle := append([]int(nil), path[k:]...)
+					o.cycles = append(o.cycles, cycle)
+					for _, c := range cycle {
+						state[c] = 2
+					}
+					return
+				}
+			}
+			return
+		}
+		state[i] = 1
+		path = append(path, i)
+		if j, ok := next[i]; ok {
+			visit(j, path)
+		}
+		if state[i] != 2 {
+			state[i] = 2
+			o.order = append(o.order, i)
+		}
+	}
+	for i, item := range o.Items {
+		if !item.IsNoop() {
+			visit(i, nil)
+		}
+	}
+	sort.Slice(o.cycles, func(a, b int) bool { return o.cycles[a][0] < o.cycles[b][0] })
+}
+
+// Errors - conflicts that prevent the plan from being applied
+func (o *TPlan) Errors(resolveCycles bool) []*TConflict {
+	if !o.isChecked {
+		o.Check()
+	}
+	ret := []*TConflict{}
+	for _, c := range o.Conflicts {
+		if c.Kind == ConflictCycle && resolveCycles {
+			continue
+		}
+		ret = append(ret, c)
+	}
+	return ret
+}
+
+// Apply - renames files if the plan is conflict free. Cycles are resolved through temporary
+// names if resolveCycles is true. fn does the rename (e.g. (*journal.TJournal).Rename).
+func (o *TPlan) Apply(resolveCycles bool, fn func(src, dst, schema string) error) error {
+	errs := o.Errors(resolveCycles)
+	if len(errs) > 0 {
+		list := []string{}
+		for _, c := range errs {
+			list = append(list, c.Message)
+		}
+		return fmt.Errorf("rename plan has conflict(s):\n  %v", strings.Join(list, "\n  "))
+	}
+
+	// the chains are applied from the end; an item of a chain ending in a cycle goes after the cycle
+	inCycle := map[int]bool{}
+	for _, cycle := range o.cycles {
+		for _, i := range cycle {
+			inCycle[i] = true
+		}
+	}
+	for _, cycle := range o.cycles {
+		if err := o.applyCycle(cycle, fn); err != nil {
+			return err
+		}
+	}
+	for _, i := range o.order {
+		if inCycle[i] {
+			continue
+		}
+		item := o.Items[i]
+		if err := fn(item.Source, item.Target, item.Schema); err != nil {
+			return fmt.Errorf("cannot rename %v -> %v: %v", item.Source, item.Target, err)
+		}
+	}
+	return nil
+}
+
+// applyCycle - moves the first item to a temporary name, then applies the rest
+// of the cycle in reverse order and moves the temporary file to its target
+func (o *TPlan) applyCycle(cycle []int, fn func(src, dst, schema string) error) error {
+	first := o.Items[cycle[0]]
+	tmp, err := tempName(first.absSource)
+	if err != nil {
+		return err
+	}
+	if err := fn(first.Source, tmp, first.Schema); err != nil {
+		return fmt.Errorf("cannot rename %v -> %v: %v", first.Source, tmp, err)
+	}
+	for k := len(cycle) - 1; k > 0; k-- {
+		item := o.Items[cycle[k]]
+		if err := fn(item.Source, item.Target, item.Schema); err != nil {
+			return fmt.Errorf("cannot rename %v -> %v: %v", item.Source, item.Target, err)
+		}
+	}
+	if err := fn(tmp, first.Target, first.Schema); err != nil {
+		return fmt.Errorf("cannot rename %v -> %v: %v", tmp, first.Target, err)
+	}
+	return nil
+}
+
+func tempName(path string) (string, error) {
+	dir, base := filepath.Split(path)
+	for i := 0; i < 1000; i++ {
+		ret := filepath.Join(dir, fmt.Sprintf(".renplan-%d-%v", i, base))
+		if _, err := os.Lstat(ret); os.IsNotExist(err) {
+			return ret, nil
+		}
+	}
+	return "", fmt.Errorf("cannot find a temporary name for %v", path)
+}
@@ -0,0 +1,100 @@
+package renplan
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func touch(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func kinds(list []*TConflict) map[string]int {
+	ret := map[string]int{}
+	for _, c := range list {
+		ret[c.Kind]++
+	}
+	return ret
+}
+
+func TestCheck(t *testing.T) {
+	dir := t.TempDir()
+	touch(t, dir, "a", "b", "c", "d", "e", "X", "f")
+	p := func(name string) string { return filepath.Join(dir, name) }
+
+	plan := New()
+	plan.Add(p("a"), p("n1"), "") // duplicate
+	plan.Add(p("b"), p("n1"), "") // duplicate
+	plan.Add(p("c"), p("N2"), "") // case collision
+	plan.Add(p("d"), p("n2"), "") // case collision
+	plan.Add(p("e"), p("f"), "")  // exists
+	plan.Add(p("X"), p("x"), "")  // case only rename of the same file is not a conflict
+	plan.Add(p("f"), p("f"), "")  // noop
+	have := kinds(plan.Check())
+	want := map[string]int{ConflictDuplicate: 1, ConflictCase: 1, ConflictExists: 1}
+	for k, v := range want {
+		if have[k] != v {
+			t.Errorf("%v: have %v, want %v (%v)", k, have[k], v, plan.Conflicts)
+		}
+	}
+	if err := plan.Apply(true, osRename); err == nil {
+		t.Errorf("Apply() has no error")
+	}
+	// nothing is renamed
+	for _, name := range []string{"a", "b", "c", "d", "e", "X"} {
+		if _, err := os.Stat(p(name)); err != nil {
+			t.Errorf("%v: %v", name, err)
+		}
+	}
+}
+
+func TestApplyCycles(t *testing.T) {
+	dir := t.TempDir()
+	touch(t, dir, "a", "b", "c", "d", "e")
+	p := func(name string) string { return filepath.Join(dir, name) }
+
+	plan := New()
+	plan.Add(p("a"), p("b"), "") // a -> b -> c -> a
+	plan.Add(p("b"), p("c"), "")
+	plan.Add(p("c"), p("a"), "")
+	plan.Add(p("d"), p("e"), "") // chain d -> e -> f
+	plan.Add(p("e"), p("f"), "")
+
+	have := kinds(plan.Check())
+	if len(have) != 1 || have[ConflictCycle] != 1 {
+		t.Fatalf("unexpected conflicts %v", plan.Conflicts)
+	}
+	if err := plan.Apply(false, osRename); err == nil {
+		t.Fatalf("Apply() without cycle resolution has no error")
+	}
+	n := 0
+	err := plan.Apply(true, func(src, dst, schema string) error {
+		n++
+		return os.Rename(src, dst)
+	})
+	if err != nil {
+		t.Fatalf("Apply() error: %v", err)
+	}
+	if n != 6 {
+		t.Errorf("have %v renames, want 6", n)
+	}
+	for name, want := range map[string]string{"b": "a", "c": "b", "a": "c", "e": "d", "f": "e"} {
+		data, err := os.ReadFile(p(name))
+		if err != nil || string(data) != want {
+			t.Errorf("%v: have %q (%v), want %q", name, data, err, want)
+		}
+	}
+	if _, err := os.Stat(p("d")); err == nil {
+		t.Errorf("d still exists")
+	}
+}
+
+func osRename(src, dst, schema string) error {
+	return os.Rename(src, dst)
+}
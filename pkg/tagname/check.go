@@ -2,7 +2,9 @@ package tagname
 
 import (
 	"fmt"
+	"sort"
 	"strings"
+	"sync"
 )
 
 type tCheckContext struct {
@@ -11,6 +13,8 @@ type tCheckContext struct {
 	TabInvalidTypes   map[string]uint8
 	TabInvalidValues  map[string]uint8
 	TabValidTypes     map[string]uint8
+	Image             tImageRules            // limits of a poster image checked deeply, set by check profiles only
+	Video             map[string]tVideoRules // limits of the video stream by sdhd
 }
 
 var (
@@ -31,6 +35,7 @@ var (
 		TabInvalidTypes:   map[string]uint8{},
 		TabInvalidValues:  map[string]uint8{},
 		TabValidTypes:     map[string]uint8{},
+		Video:             builtinVideoRules,
 	}
 	checkContextForPosters = &tCheckContext{
 		ListMustHaveTypes: []string{"sdhd", "sizetag"},
@@ -39,6 +44,14 @@ var (
 		TabInvalidValues:  map[string]uint8{},
 		TabValidTypes:     map[string]uint8{},
 	}
+	// a poster with the 'logo' sizetag
+	checkContextForLogos = &tCheckContext{
+		ListMustHaveTypes: []string{"sdhd", "sizetag"},
+		TabNonUniqueTypes: map[string]uint8{},
+		TabInvalidTypes:   map[string]uint8{},
+		TabInvalidValues:  map[string]uint8{},
+		TabValidTypes:     map[string]uint8{},
+	}
 	checkContextForGpPosters = &tCheckContext{
 		ListMustHaveTypes: []string{"sizetag"},
 		TabNonUniqueTypes: map[string]uint8{},
@@ -46,6 +59,30 @@ var (
 		TabInvalidValues:  map[string]uint8{},
 		TabValidTypes:     map[string]uint8{},
 	}
+	checkContextForExtras = &tCheckContext{
+		ListMustHaveTypes: []string{"sdhd"},
+		TabNonUniqueTypes: map[string]uint8{},
+		TabInvalidTypes:   map[string]uint8{},
+		TabInvalidValues:  map[string]uint8{},
+		TabValidTypes:     map[string]uint8{},
+		Video:             builtinVideoRules,
+	}
+	// an audio track (a dub) without video
+	checkContextForAudio = &tCheckContext{
+		ListMustHaveTypes: []string{"sdhd", "atag"},
+		TabNonUniqueTypes: map[string]uint8{},
+		TabInvalidTypes:   map[string]uint8{"qtag": 0, "stag": 0, "sizetag": 0, "hardsubtag": 0},
+		TabInvalidValues:  map[string]uint8{},
+		TabValidTypes:     map[string]uint8{},
+	}
+	// a subtitle package without video and audio
+	checkContextForSubtitles = &tCheckContext{
+		ListMustHaveTypes: []string{"sdhd", "stag"},
+		TabNonUniqueTypes: map[string]uint8{},
+		TabInvalidTypes:   map[string]uint8{"qtag": 0, "atag": 0, "sizetag": 0, "hardsubtag": 0},
+		TabInvalidValues:  map[string]uint8{},
+		TabValidTypes:     map[string]uint8{},
+	}
 )
 
 func updateCheckContext(out *tCheckContext, in1, in2 *tCheckContext) *tCheckContext {
@@ -58,52 +95,135 @@ func updateCheckContext(out *tCheckContext, in1, in2 *tCheckContext) *tCheckCont
 		TabInvalidTypes:   updateTable(nil, in1.TabInvalidTypes, in2.TabInvalidTypes),
 		TabInvalidValues:  updateTable(nil, in1.TabInvalidValues, in2.TabInvalidValues),
 		TabValidTypes:     updateTable(nil, in1.TabValidTypes, in2.TabValidTypes),
+		Image:             updateImageRules(in1.Image, in2.Image),
+		Video:             updateVideoRules(in1.Video, in2.Video),
 	}
 }
 
+// checkContextMtx guards lazy initialization of the composed contexts
+var checkContextMtx sync.Mutex
+
 var filmsCheckContext *tCheckContext
 
 func getFilmsCC() *tCheckContext {
+	checkContextMtx.Lock()
+	defer checkContextMtx.Unlock()
 	if filmsCheckContext != nil {
 		return filmsCheckContext
 	}
-	filmsCheckContext = updateCheckContext(nil, defaultCheckContext, checkContextForFilms)
+	filmsCheckContext = composeCheckContext(checkContextForFilms, "film")
 	return filmsCheckContext
 }
 
+// getVideoCC - the context of a content type with a video stream
+func getVideoCC(typ string) *tCheckContext {
+	if typ == "extra" {
+		return getExtrasCC()
+	}
+	return getFilmsCC()
+}
+
 var postersCheckContext *tCheckContext
 
 func getPostersCC() *tCheckContext {
+	checkContextMtx.Lock()
+	defer checkContextMtx.Unlock()
 	if postersCheckContext != nil {
 		return postersCheckContext
 	}
-	postersCheckContext = updateCheckContext(nil, defaultCheckContext, checkContextForPosters)
+	postersCheckContext = composeCheckContext(checkContextForPosters, "poster")
 	return postersCheckContext
 }
 
+var logosCheckContext *tCheckContext
+
+func getLogosCC() *tCheckContext {
+	checkContextMtx.Lock()
+	defer checkContextMtx.Unlock()
+	if logosCheckContext != nil {
+		return logosCheckContext
+	}
+	logosCheckContext = composeCheckContext(checkContextForLogos, "poster", "logo")
+	return logosCheckContext
+}
+
+// getPosterCC - the context of logos for a poster with the 'logo' sizetag and of posters otherwise
+func getPosterCC(tags *TTags) *tCheckContext {
+	if size, _ := tags.GetTag("sizetag"); size == "logo" {
+		return getLogosCC()
+	}
+	return getPostersCC()
+}
+
 var gpPostersCheckContext *tCheckContext
 
 func getGpPostersCC() *tCheckContext {
+	checkContextMtx.Lock()
+	defer checkContextMtx.Unlock()
 	if gpPostersCheckContext != nil {
 		return gpPostersCheckContext
 	}
-	gpPostersCheckContext = updateCheckContext(nil, defaultCheckContext, checkContextForGpPosters)
+	gpPostersCheckContext = composeCheckContext(checkContextForGpPosters, "gp")
 	return gpPostersCheckContext
 }
 
+var extrasCheckContext *tCheckContext
+
+func getExtrasCC() *tCheckContext {
+	checkContextMtx.Lock()
+	defer checkContextMtx.Unlock()
+	if extrasCheckContext != nil {
+		return extrasCheckContext
+	}
+	extrasCheckContext = composeCheckContext(checkContextForExtras, "extra")
+	return extrasCheckContext
+}
+
+var audioCheckContext *tCheckContext
+
+func getAudioCC() *tCheckContext {
+	checkContextMtx.Lock()
+	defer checkContextMtx.Unlock()
+	if audioCheckContext != nil {
+		return audioCheckContext
+	}
+	audioCheckContext = composeCheckContext(checkContextForAudio, "audio")
+	return audioCheckContext
+}
+
+var subtitlesCheckContext *tCheckContext
+
+func getSubtitlesCC() *tCheckContext {
+	checkContextMtx.Lock()
+	defer checkContextMtx.Unlock()
+	if subtitlesCheckContext != nil {
+		return subtitlesCheckContext
+	}
+	subtitlesCheckContext = composeCheckContext(checkContextForSubtitles, "subtitle")
+	return subtitlesCheckContext
+}
+
 func updateList(out, in1, in2 []string) []string {
 	if out != nil {
 		return out
 	}
-	for _, s := range in1 {
-		out = append(out, s)
-	}
-	for _, s := range in2 {
-		out = append(out, s)
+	for _, s := range append(append([]string{}, in1...), in2...) {
+		if !isInList(out, s) {
+			out = append(out, s)
+		}
 	}
 	return out
 }
 
+func isInList(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
 func updateTable(out, in1, in2 map[string]uint8) map[string]uint8 {
 	if out != nil {
 		return out
@@ -131,16 +251,21 @@ func isNotExist(table map[string]uint8, typ string) bool {
 	return !ok
 }
 
+// CheckTags -
 func CheckTags(tags *TTags) error {
+	return checkTags(tags).Err()
+}
+
+func checkTags(tags *TTags) TDiagnostics {
 
 	err := tags.State()
 	if err != nil {
-		return err
+		return Diagnostics(err)
 	}
 
 	typ, err := tags.GetTag("type")
 	if err != nil {
-		return err
+		return TDiagnostics{newDiag(DiagMissingTag, "type", "", "", err.Error())}
 	}
 
 	cc := defaultCheckContext
@@ -148,25 +273,42 @@ func CheckTags(tags *TTags) error {
 	case "film", "trailer", "teaser":
 		cc = getFilmsCC()
 	case "poster", "poster.logo":
-		cc = getPostersCC()
+		cc = getPosterCC(tags)
 	case "poster.gp":
 		cc = getGpPostersCC()
+	case "extra":
+		cc = getExtrasCC()
+	case "audio":
+		cc = getAudioCC()
+	case "subtitle":
+		cc = getSubtitlesCC()
 	default:
-		return fmt.Errorf("check: unsupported tag 'type': %q", typ)
+		return TDiagnostics{newDiag(DiagUnsupportedType, "type", "", typ,
+			fmt.Sprintf("check: unsupported tag 'type': %q", typ))}
 	}
 
+	ret := TDiagnostics{}
 	// o := schema.checker
 	for _, typ := range cc.ListMustHaveTypes {
 		// fmt.Println("###")
 		_, ok := tags.byType[typ]
 		if !ok {
-			return fmt.Errorf("%q type does not exist", typ)
+			ret = append(ret, newDiag(DiagMissingTag, typ, typ, "",
+				fmt.Sprintf("%q type does not exist", typ)))
 		}
 	}
-	errors := []string{}
-	for typ, list := range tags.byType {
+	types := make([]string, 0, len(tags.byType))
+	for typ := range tags.byType {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+	for _, typ := range types {
+		list := tags.byType[typ]
 		if strings.HasPrefix(typ, "ERR_") {
-			errors = append(errors, fmt.Sprintf("%v: %v", typ, strings.Join(list, ", ")))
+			for _, val := range list {
+				ret = append(ret, newDiag(DiagMalformedTag, typ, "", val,
+					fmt.Sprintf("%v: %v", typ, val)))
+			}
 			continue
 		}
 		// if typ == "UNKNOWN_TAG" {
@@ -176,41 +318,57 @@ func CheckTags(tags *TTags) error {
 		// continue
 		// }
 		if typ == "INVALID_TAG" {
-			return fmt.Errorf("invalid tag(s) are present: %v", list)
+			ret = append(ret, newDiag(DiagInvalidTag, typ, "", strings.Join(list, ", "),
+				fmt.Sprintf("invalid tag(s) are present: %v", list)))
+			continue
 		}
 		if _, ok := cc.TabNonUniqueTypes[typ]; !ok {
 			if len(list) > 1 {
-				return fmt.Errorf("%q type must be unique", typ)
+				ret = append(ret, newDiag(DiagNonUniqueTag, typ, "", strings.Join(list, ", "),
+					fmt.Sprintf("%q type must be unique", typ)))
 			}
 		}
 		if isNotExist(cc.TabValidTypes, typ) {
-			return fmt.Errorf("%q is not a valid tag type", typ)
+			ret = append(ret, newDiag(DiagInvalidTagType, typ, "", strings.Join(list, ", "),
+				fmt.Sprintf("%q is not a valid tag type", typ)))
+			continue
 		}
 		if isExist(cc.TabInvalidTypes, typ) {
-			return fmt.Errorf("%q is an invalid tag type: %q", typ, list)
+			ret = append(ret, newDiag(DiagInvalidTagType, typ, "", strings.Join(list, ", "),
+				fmt.Sprintf("%q is an invalid tag type: %q", typ, list)))
+			continue
 		}
 		for _, val := range list {
 			// if !isExist(o.tabValid, val) {
 			// return fmt.Errorf("tag (%q,%q) has not a valid value", typ, val)
 			// }
 			if isExist(cc.TabInvalidValues, val) {
-				return fmt.Errorf("tag (%q,%q) has an invalid value", typ, val)
+				ret = append(ret, newDiag(DiagInvalidTagValue, typ, "", val,
+					fmt.Sprintf("tag (%q,%q) has an invalid value", typ, val)))
+			}
+			if typ == "exx" {
+				if _, _, err := parseExx(val); err != nil {
+					ret = append(ret, newDiag(DiagInvalidTagValue, typ, "", val, err.Error()))
+				}
 			}
 		}
 	}
-	if len(errors) > 0 {
-		return fmt.Errorf("some error(s):\n        %v", strings.Join(errors, "\n        "))
+	if len(ret) > 0 {
+		return ret
 	}
 
 	switch typ {
 	case "film", "trailer", "teaser":
-		return checkFilmsOrTrailers(tags, typ)
+		return Diagnostics(checkFilmsOrTrailers(tags, typ))
 	case "poster", "poster.logo":
-		return checkPostersOrLogo(tags, typ)
+		return Diagnostics(checkPostersOrLogo(tags, typ))
 	case "poster.gp":
-		return checkGpPosters(tags, typ)
+		return Diagnostics(checkGpPosters(tags, typ))
+	case "extra", "audio", "subtitle":
+		return nil
 	default:
-		return fmt.Errorf("check: unsupported tag 'type': %q", typ)
+		return TDiagnostics{newDiag(DiagUnsupportedType, "type", "", typ,
+			fmt.Sprintf("check: unsupported tag 'type': %q", typ))}
 	}
 	// unreachable
 }
@@ -2,8 +2,11 @@ package tagname
 
 import (
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
+
+	"github.com/malashin/ffinfo"
 )
 
 func parseSize(str string) (int, int, error) {
@@ -23,70 +26,75 @@ func parseSize(str string) (int, int, error) {
 	return w, h, nil
 }
 
-func checkSize(tn *TTagname, typ string, width, height int) error {
+func checkSize(tn *TTagname, typ string, width, height int) *TDiagnostic {
 	switch typ {
 	case "poster", "poster.gp":
 		size, err := tn.GetTag("sizetag")
 		if err != nil {
-			return err
+			return newDiag(DiagMissingTag, "sizetag", "sizetag", "", err.Error())
 		}
+		have := TResolution{width, height}.String()
 		if size == "logo" {
 			if 900 > width || width > 1500 {
-				return fmt.Errorf("improper size (want width<=1500, have width=%v)", width)
+				return newDiag(DiagSizeMismatch, "sizetag", "900<=width<=1500", have,
+					fmt.Sprintf("improper size (want width<=1500, have width=%v)", width))
 			}
 			return nil
 		}
 		w, h, err := parseSize(size)
 		if err != nil {
-			return err
+			return newDiag(DiagMalformedTag, "sizetag", "", size, err.Error())
 		}
 		if w != width || h != height {
-			return fmt.Errorf("improper size (want %vx%v, have %vx%v)", width, height, w, h)
+			return newDiag(DiagSizeMismatch, "sizetag", size, have,
+				fmt.Sprintf("improper size (want %vx%v, have %vx%v)", width, height, w, h))
 		}
 	}
 	return nil
 }
 
-func checkDeep(tagname *TTagname) error {
+func checkDeep(tagname *TTagname) TDiagnostics {
 	typ, err := tagname.GetType()
 	if err != nil {
-		return err
+		return TDiagnostics{newDiag(DiagMissingTag, "type", "", "", err.Error())}
 	}
+	ret := TDiagnostics{}
 	switch typ {
 	default:
-		if err == nil {
-			err = fmt.Errorf(fmtCheckError("unsupported type", typ, "", tagname.src))
-		}
+		// deep check of other types is not supported yet
 		return nil
 	case "poster", "poster.logo", "poster.gp":
-		// filePath := filepath.Join(tagname.dir, tagname.src)
-		// file, err := ffinfo.Probe(filePath)
-		info, err := tagname.FFInfo()
+		info, err := tagname.ImageInfo()
 		if err != nil {
-			return err
+			return TDiagnostics{newDiag(DiagProbeError, "", "", "", err.Error())}
 		}
-		w, h := 0, 0
-		if len(info.Streams) > 0 && info.Streams[0].CodecType == "video" {
-			w = info.Streams[0].Width
-			h = info.Streams[0].Height
-		} else {
-			return fmt.Errorf("failed to read file canvas size")
+		if d := checkSize(tagname, typ, info.Width, info.Height); d != nil {
+			ret = append(ret, d)
 		}
-		err = checkSize(tagname, typ, w, h)
-		if err != nil {
-			return err
+		ext, _ := tagname.GetTag("ext")
+		if d := checkImageExtension(ext, info); d != nil {
+			ret = append(ret, d)
+		}
+		cc := getGpPostersCC()
+		if typ != "poster.gp" {
+			cc = getPosterCC(tagname.tags)
 		}
+		ret = append(ret, checkImage(info, cc.Image)...)
 
-	case "film", "trailer":
+	case "audio":
+		return checkStreamsOnly(tagname, "audio")
+	case "subtitle":
+		return checkStreamsOnly(tagname, "subtitle")
+	case "film", "trailer", "extra":
 		format, err := tagname.Describe()
 		if err != nil {
-			return err
+			return Diagnostics(err)
 		}
 		// filePath := filepath.Join(tagname.dir, tagname.src)
 		// file, err := ffinfo.Probe(filePath)
 		info, err := tagname.FFInfo()
 		if err != nil {
-			return err
+			return TDiagnostics{newDiag(DiagProbeError, "", "", "", err.Error())}
 		}
 
 		type tduration struct {
@@ -102,21 +110,29 @@ func checkDeep(tagname *TTagname) error {
 			// fmt.Printf("#%v: %v\n", index, dur)
 			switch s.CodecType {
 			default:
-				return fmt.Errorf(fmtCheckError("unsupported codec type", s.CodecType, "", tagname.src))
+				return TDiagnostics{newDiag(DiagUnsupportedCodec, "", "", s.CodecType,
+					fmtCheckError("unsupported codec type", s.CodecType, "", tagname.src))}
 			case "video":
 				dur, err := info.StreamDuration(index)
 				if dur < 0 {
-					return fmt.Errorf("stream #%v of file %q: %v", index, tagname.src, err)
+					return TDiagnostics{newDiag(DiagStreamDuration, "", "", "",
+						fmt.Sprintf("stream #%v of file %q: %v", index, tagname.src, err))}
+				}
+				if err != nil {
+					d := newDiag(DiagStreamDuration, "", "", "", fmt.Sprintf("%v: %v", tagname.src, err))
+					d.Severity = SeverityWarning
+					ret = append(ret, d)
 				}
-				log.Warningf(err, tagname.src)
 				videoDur = tduration{idx: index, dur: dur}
 
 				if index != 0 {
-					return fmt.Errorf(fmtCheckError("index of the video stream", "0", strconv.Itoa(index), tagname.src))
+					ret = append(ret, newDiag(DiagVideoStreamIndex, "", "0", strconv.Itoa(index),
+						fmtCheckError("index of the video stream", "0", strconv.Itoa(index), tagname.src)))
 				}
 				realRes := TResolution{s.Width, s.Height}
 				if format.resolution != realRes {
-					return fmt.Errorf(fmtCheckError("resolution", format.resolution.String(), realRes.String(), tagname.src))
+					ret = append(ret, newDiag(DiagResolutionMismatch, "sdhd", format.resolution.String(), realRes.String(),
+						fmtCheckError("resolution", format.resolution.String(), realRes.String(), tagname.src)))
 				}
 				sar := s.SampleAspectRatio
 				// fix ffmpeg SAR
@@ -125,14 +141,21 @@ func checkDeep(tagname *TTagname) error {
 					sar = "1:1"
 				}
 				if format.Sar != "" && format.Sar != sar {
-					return fmt.Errorf(fmtCheckError("SAR", format.Sar, sar, tagname.src))
+					ret = append(ret, newDiag(DiagSarMismatch, "qtag", format.Sar, sar,
+						fmtCheckError("SAR", format.Sar, sar, tagname.src)))
 				}
+				ret = append(ret, compareVideo(tagname, format, s)...)
 			case "audio":
 				dur, err := info.StreamDuration(index)
 				if dur < 0 {
-					return fmt.Errorf("get stream duration of stream #%v of file %q: %v", index, tagname.src, err)
+					return TDiagnostics{newDiag(DiagStreamDuration, "", "", "",
+						fmt.Sprintf("get stream duration of stream #%v of file %q: %v", index, tagname.src, err))}
+				}
+				if err != nil {
+					d := newDiag(DiagStreamDuration, "", "", "", fmt.Sprintf("%v: %v", tagname.src, err))
+					d.Severity = SeverityWarning
+					ret = append(ret, d)
 				}
-				log.Warningf(err, tagname.src)
 				duration = append(duration, tduration{idx: index, dur: dur})
 
 				lang := s.Tags.Language
@@ -149,21 +172,11 @@ func checkDeep(tagname *TTagname) error {
 			}
 		}
 
-		if len(realA) == 1 && realA[0].Language != "---" {
-			realA[0].Language = "---"
-		}
-		if len(format.Audio) == 1 && format.Audio[0].Language != "---" {
-			format.Audio[0].Language = "---"
+		if d := compareAudio(tagname, format.Audio, realA); d != nil {
+			ret = append(ret, d)
 		}
-		a1 := audioToStr(format.Audio)
-		a2 := audioToStr(realA)
-		if a1 != a2 {
-			return fmt.Errorf(fmtCheckError("audio", a1, a2, tagname.src))
-		}
-		s1 := strings.Join(format.Subtitle, " ")
-		s2 := strings.Join(realS, " ")
-		if s1 != s2 {
-			return fmt.Errorf(fmtCheckError("subtitle", s1, s2, tagname.src))
+		if d := compareSubtitle(tagname, format.Subtitle, realS); d != nil {
+			ret = append(ret, d)
 		}
 
 		ok := true
@@ -179,9 +192,183 @@ func checkDeep(tagname *TTagname) error {
 				diff := videoDur.dur - v.dur
 				errStr += fmt.Sprintf("\n            #%v: %.4f seconds (%+.4f)", v.idx, v.dur, diff)
 			}
-			return fmt.Errorf(errStr)
+			ret = append(ret, newDiag(DiagDurationMismatch, "", fmt.Sprintf("%.4f", videoDur.dur), "", errStr))
 		}
 	} // switch typ
+	return ret
+}
+
+// checkStreamsOnly - the file must contain streams of the codec type only (an audio-only dub
+// or a subtitle package) and the atag or the stag must match them
+func checkStreamsOnly(tagname *TTagname, codecType string) TDiagnostics {
+	info, err := tagname.FFInfo()
+	if err != nil {
+		return TDiagnostics{newDiag(DiagProbeError, "", "", "", err.Error())}
+	}
+	ret := TDiagnostics{}
+	realA := []TAudio{}
+	realS := []string{}
+	for index, s := range info.Streams {
+		if s.CodecType != codecType {
+			ret = append(ret, newDiag(DiagUnexpectedStream, "type", codecType, s.CodecType,
+				fmtCheckError(fmt.Sprintf("stream #%v", index), codecType, s.CodecType, tagname.src)))
+			continue
+		}
+		lang := s.Tags.Language
+		if lang == "" || len(lang) != 3 {
+			lang = "---"
+		}
+		realA = append(realA, TAudio{lang, s.Channels})
+		realS = append(realS, lang)
+	}
+	if len(realA) == 0 {
+		return append(ret, newDiag(DiagMissingStream, "type", codecType, "",
+			fmtCheckError("no stream of type", codecType, "", tagname.src)))
+	}
+
+	switch codecType {
+	case "audio":
+		want, err := tagname.GetAudio()
+		if err != nil {
+			return append(ret, newDiag(DiagMalformedTag, "atag", "", "", err.Error()))
+		}
+		if d := compareAudio(tagname, want, realA); d != nil {
+			ret = append(ret, d)
+		}
+	case "subtitle":
+		want, err := tagname.GetSubtitle()
+		if err != nil {
+			return append(ret, newDiag(DiagMalformedTag, "stag", "", "", err.Error()))
+		}
+		if d := compareSubtitle(tagname, want, realS); d != nil {
+			ret = append(ret, d)
+		}
+	}
+	return ret
+}
+
+// compareVideo - checks technical parameters of the video stream. Values ffprobe does not know
+// are accepted unless the format requires HDR colour metadata.
+func compareVideo(tagname *TTagname, format *TFormat, s ffinfo.Stream) TDiagnostics {
+	ret := TDiagnostics{}
+	// the frame rate and the field order of a PAL master follow qtag
+	tagType := "sdhd"
+	if tagname.isPalMaster() {
+		tagType = "qtag"
+	}
+	rate := s.AvgFrameRate
+	if !isKnownFrameRate(rate) {
+		rate = s.RFrameRate
+	}
+	if len(format.FrameRates) > 0 && isKnownFrameRate(rate) && !isInFrameRates(format.FrameRates, rate) {
+		want := strings.Join(format.FrameRates, "|")
+		ret = append(ret, newDiag(DiagFrameRateMismatch, tagType, want, rate,
+			fmtCheckError("frame rate", want, rate, tagname.src)))
+	}
+	if d := compareVideoValue(tagname, DiagFieldOrderMismatch, tagType, "field order", format.FieldOrders, s.FieldOrder, false); d != nil {
+		ret = append(ret, d)
+	}
+	if d := compareVideoValue(tagname, DiagPixFmtMismatch, "sdhd", "pixel format", format.PixFmts, s.PixFmt, false); d != nil {
+		ret = append(ret, d)
+	}
+	if depth := bitDepth(s); format.BitDepth > 0 && depth > 0 && depth != format.BitDepth {
+		want, have := strconv.Itoa(format.BitDepth), strconv.Itoa(depth)
+		ret = append(ret, newDiag(DiagBitDepthMismatch, "sdhd", want, have,
+			fmtCheckError("bit depth", want, have, tagname.src)))
+	}
+	if d := compareVideoValue(tagname, DiagColorMismatch, "sdhd", "colour primaries", format.ColorPrimaries, s.ColorPrimaries, format.Hdr); d != nil {
+		ret = append(ret, d)
+	}
+	if d := compareVideoValue(tagname, DiagColorMismatch, "sdhd", "colour transfer", format.ColorTransfers, s.ColorTransfer, format.Hdr); d != nil {
+		ret = append(ret, d)
+	}
+	return ret
+}
+
+func compareVideoValue(tagname *TTagname, code, tagType, title string, want []string, have string, isRequired bool) *TDiagnostic {
+	if len(want) == 0 || isInList(want, have) {
+		return nil
+	}
+	if have == "" || have == "unknown" {
+		if !isRequired {
+			return nil
+		}
+		have = "unknown"
+	}
+	list := strings.Join(want, "|")
+	return newDiag(code, tagType, list, have, fmtCheckError(title, list, have, tagname.src))
+}
+
+// parseFrameRate - parses 'num/den' or a decimal number, returns 0 if the rate is unknown
+func parseFrameRate(s string) float64 {
+	num, den, ok := strings.Cut(s, "/")
+	if !ok {
+		den = "1"
+	}
+	n, err1 := strconv.ParseFloat(num, 64)
+	d, err2 := strconv.ParseFloat(den, 64)
+	if err1 != nil || err2 != nil || d == 0 || n <= 0 {
+		return 0
+	}
+	return n / d
+}
+
+func isKnownFrameRate(s string) bool {
+	return parseFrameRate(s) > 0
+}
+
+func isInFrameRates(list []string, rate string) bool {
+	r := parseFrameRate(rate)
+	for _, s := range list {
+		if math.Abs(parseFrameRate(s)-r) < 0.005 {
+			return true
+		}
+	}
+	return false
+}
+
+// bitDepth - bits per sample of the video stream, 0 if unknown
+func bitDepth(s ffinfo.Stream) int {
+	if depth, err := strconv.Atoi(s.BitsPerRawSample); err == nil && depth > 0 {
+		return depth
+	}
+	switch {
+	case s.PixFmt == "":
+		return 0
+	case strings.Contains(s.PixFmt, "p10"):
+		return 10
+	case strings.Contains(s.PixFmt, "p12"):
+		return 12
+	}
+	return 8
+}
+
+// compareAudio - the language of a single track is not compared
+func compareAudio(tagname *TTagname, want, real []TAudio) *TDiagnostic {
+	if len(real) == 1 && real[0].Language != "---" {
+		real = []TAudio{{"---", real[0].Channels}}
+	}
+	if len(want) == 1 && want[0].Language != "---" {
+		want = []TAudio{{"---", want[0].Channels}}
+	}
+	a1 := audioToStr(want)
+	a2 := audioToStr(real)
+	if a1 != a2 {
+		return newDiag(DiagAudioMismatch, "atag", a1, a2, fmtCheckError("audio", a1, a2, tagname.src))
+	}
+	return nil
+}
+
+// compareSubtitle - an unknown language of a single track (e.g. of an .srt file) is not compared
+func compareSubtitle(tagname *TTagname, want, real []string) *TDiagnostic {
+	if len(real) == 1 && real[0] == "---" && len(want) == 1 {
+		return nil
+	}
+	s1 := strings.Join(want, " ")
+	s2 := strings.Join(real, " ")
+	if s1 != s2 {
+		return newDiag(DiagSubtitleMismatch, "stag", s1, s2, fmtCheckError("subtitle", s1, s2, tagname.src))
+	}
 	return nil
 }
 
@@ -0,0 +1,137 @@
+package tagname
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/malashin/ffinfo"
+)
+
+func testVideo(w, h int, rate, field, pixFmt, primaries, transfer string) ffinfo.Stream {
+	s := testStream("video", "h264", w, h, 0, "")
+	s.AvgFrameRate = rate
+	s.FieldOrder = field
+	s.PixFmt = pixFmt
+	s.ColorPrimaries = primaries
+	s.ColorTransfer = transfer
+	return s
+}
+
+const testVideoProfile = `
+[sdr4k]
+extra.4k.pixfmts   = yuv420p
+extra.4k.bitdepth  = 8
+extra.4k.primaries = bt709
+extra.4k.transfers = bt709
+extra.4k.hdr       = no
+sd.pixfmts         = yuv420p yuv422p
+`
+
+func TestCompareVideo(t *testing.T) {
+	defer SetCheckProfile("")
+	profiles, err := ParseCheckProfiles(testVideoProfile)
+	if err != nil {
+		t.Fatalf("ParseCheckProfiles() error: %v", err)
+	}
+	RegisterCheckProfile(profiles[0])
+
+	table := []struct {
+		profile string
+		input   string
+		stream  ffinfo.Stream
+		codes   []string
+		actual  []string
+	}{
+		{"", "the_name_2018__hd.mp4", testVideo(1920, 1080, "24000/1001", "progressive", "yuv420p", "bt709", "bt709"), nil, nil},
+		// unknown values are accepted for sdr
+		{"", "the_name_2018__hd.mp4", testVideo(1920, 1080, "0/0", "", "", "", "unknown"), nil, nil},
+		{"", "the_name_2018__hd.mp4", testVideo(1920, 1080, "25/1", "tt", "yuv422p10le", "bt2020", "smpte2084"),
+			[]string{DiagFieldOrderMismatch, DiagPixFmtMismatch, DiagBitDepthMismatch, DiagColorMismatch, DiagColorMismatch},
+			[]string{"tt", "yuv422p10le", "10", "bt2020", "smpte2084"}},
+		{"", "the_name_2018__hd.mp4", testVideo(1920, 1080, "15/1", "progressive", "yuv420p", "bt709", "bt709"),
+			[]string{DiagFrameRateMismatch}, []string{"15/1"}},
+		{"", "the_name_2018__4k.mp4", testVideo(3840, 2160, "25", "progressive", "yuv420p10le", "bt2020", "arib-std-b67"), nil, nil},
+		// 4k must be hdr
+		{"", "the_name_2018__4k.mp4", testVideo(3840, 2160, "25/1", "progressive", "yuv420p", "bt709", ""),
+			[]string{DiagPixFmtMismatch, DiagBitDepthMismatch, DiagColorMismatch, DiagColorMismatch},
+			[]string{"yuv420p", "8", "bt709", "unknown"}},
+		{"", "the_name_2018__4k.extra.mp4", testVideo(3840, 2160, "25/1", "progressive", "yuv420p", "bt709", ""),
+			[]string{DiagPixFmtMismatch, DiagBitDepthMismatch, DiagColorMismatch, DiagColorMismatch},
+			[]string{"yuv420p", "8", "bt709", "unknown"}},
+		// a profile overrides the built-in limits
+		{"sdr4k", "the_name_2018__4k.extra.mp4", testVideo(3840, 2160, "25/1", "progressive", "yuv420p", "bt709", ""), nil, nil},
+		{"sdr4k", "the_name_2018__4k.mp4", testVideo(3840, 2160, "25/1", "progressive", "yuv420p", "bt709", ""),
+			[]string{DiagPixFmtMismatch, DiagBitDepthMismatch, DiagColorMismatch, DiagColorMismatch},
+			[]string{"yuv420p", "8", "bt709", "unknown"}},
+		// qtag describes an interlaced PAL master
+		{"", "the_name_2018__sd_q0w2.mpg", testVideo(720, 576, "25/1", "tt", "yuv420p", "", ""), nil, nil},
+		{"", "the_name_2018__sd_q0w2.mpg", testVideo(720, 576, "25/1", "progressive", "yuv420p", "", ""),
+			[]string{DiagFieldOrderMismatch}, []string{"progressive"}},
+		{"", "the_name_2018__sd_q0w2.mpg", testVideo(720, 576, "30000/1001", "bb", "yuv420p", "", ""),
+			[]string{DiagFrameRateMismatch}, []string{"30000/1001"}},
+		{"", "the_name_2018__sd_q0w2.mpg", testVideo(720, 576, "25/1", "bb", "yuv422p", "", ""),
+			[]string{DiagPixFmtMismatch}, []string{"yuv422p"}},
+		{"sdr4k", "the_name_2018__sd_q0w2.mpg", testVideo(720, 576, "25/1", "bb", "yuv422p", "", ""), nil, nil},
+		// any frame rate and field order without qtag
+		{"", "the_name_2018__sd.mpg", testVideo(720, 576, "30000/1001", "progressive", "yuv420p", "", ""), nil, nil},
+	}
+	for _, v := range table {
+		if err := SetCheckProfile(v.profile); err != nil {
+			t.Fatalf("SetCheckProfile() error: %v", err)
+		}
+		tn, err := NewFromString("", v.input, false)
+		if err != nil {
+			t.Errorf("\n%q\nNewFromString() error: %v", v.input, err)
+			continue
+		}
+		format, err := tn.Describe()
+		if err != nil {
+			t.Errorf("\n%q\nDescribe() error: %v", v.input, err)
+			continue
+		}
+		codes, actual := []string(nil), []string(nil)
+		for _, d := range compareVideo(tn, format, v.stream) {
+			codes = append(codes, d.Code)
+			actual = append(actual, d.Actual)
+		}
+		if !reflect.DeepEqual(codes, v.codes) || !reflect.DeepEqual(actual, v.actual) {
+			t.Errorf("\n%v %q\ncodes %v %q, want %v %q", v.profile, v.input, codes, actual, v.codes, v.actual)
+		}
+	}
+}
+
+func TestVideoProfileErrors(t *testing.T) {
+	for _, src := range []string{"5k.hdr = yes", "hd.bitdepth = x", "film.hd.hdr = maybe", "hd.framerates = 25/0", "hd.colors = x"} {
+		if _, err := ParseCheckProfiles("[video]\n" + src); err == nil {
+			t.Errorf("ParseCheckProfiles(%q): error expected", src)
+		}
+	}
+}
+
+func TestCompareVideoFrameRateTag(t *testing.T) {
+	// the frame rate is blamed on qtag only if qtag sets it
+	table := []struct {
+		input   string
+		stream  ffinfo.Stream
+		tagType string
+	}{
+		{"the_name_2018__sd_q0w2.mpg", testVideo(720, 576, "30000/1001", "bb", "yuv420p", "", ""), "qtag"},
+		{"the_name_2018__hd_q0w2.mp4", testVideo(1920, 1080, "15/1", "progressive", "yuv420p", "bt709", "bt709"), "sdhd"},
+	}
+	for _, v := range table {
+		tn, err := NewFromString("", v.input, false)
+		if err != nil {
+			t.Errorf("\n%q\nNewFromString() error: %v", v.input, err)
+			continue
+		}
+		format, err := tn.Describe()
+		if err != nil {
+			t.Errorf("\n%q\nDescribe() error: %v", v.input, err)
+			continue
+		}
+		list := compareVideo(tn, format, v.stream)
+		if len(list) != 1 || list[0].Code != DiagFrameRateMismatch || list[0].TagType != v.tagType {
+			t.Errorf("\n%q\nhave %+v, want a frame rate mismatch of %q", v.input, list, v.tagType)
+		}
+	}
+}
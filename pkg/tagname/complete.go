@@ -0,0 +1,136 @@
+package tagname
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/malashin/ffinfo"
+)
+
+// DiagTagConflict - an existing tag disagrees with the probed value
+const DiagTagConflict = "tag_conflict"
+
+var imageCodecs = map[string]bool{
+	"mjpeg": true, "png": true, "bmp": true, "tiff": true, "targa": true, "webp": true, "gif": true,
+}
+
+func isImageInfo(info *ffinfo.File) bool {
+	if info == nil || len(info.Streams) != 1 {
+		return false
+	}
+	return imageCodecs[strings.ToLower(info.Streams[0].CodecName)]
+}
+
+func isSameTag(typ, a, b string) bool {
+	switch typ {
+	case "sdhd":
+		// 3d has the same resolution as hd
+		if a == "3d" {
+			a = "hd"
+		}
+		if b == "3d" {
+			b = "hd"
+		}
+	case "ext":
+		a = strings.ToLower(a)
+		b = strings.ToLower(b)
+		if a == ".jpeg" {
+			a = ".jpg"
+		}
+		if b == ".jpeg" {
+			b = ".jpg"
+		}
+	}
+	return a == b
+}
+
+// Complete - fills absent tags with the values probed from the file.
+// Existing tags that disagree with the probed values are not changed but returned as conflicts.
+func (o *TTagname) Complete() (TDiagnostics, error) {
+	info, err := o.FFInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	typ, _ := o.GetType()
+	if _, ok := o.srcTags.byType["type"]; !ok {
+		// the type was derived from the other tags, so the file content takes precedence
+		sdhd, _ := o.GetFormat()
+		switch {
+		case isImageInfo(info) && (typ == "film" || typ == ""):
+			typ = "poster"
+			if sdhd == "" {
+				typ = "poster.gp"
+			}
+		case !isImageInfo(info) && strings.HasPrefix(typ, "poster"):
+			typ = "film"
+		}
+		o.SetTag("type", typ)
+	}
+
+	ret := TDiagnostics{}
+	fill := func(tagType string, fn func(*ffinfo.File) (string, error), isDefault bool) {
+		probed, err := fn(info)
+		if err != nil {
+			ret = append(ret, newDiag(DiagProbeError, tagType, "", "", fmt.Sprintf("%v: %v", tagType, err)))
+			return
+		}
+		list := o.GetTags(tagType)
+		if len(list) == 1 && list[0] == "" {
+			list = nil
+		}
+		switch {
+		case len(list) == 0 || isDefault:
+			if probed == "" {
+				o.RemoveTags(tagType)
+				return
+			}
+			o.SetTag(tagType, probed)
+		case len(list) > 1 || !isSameTag(tagType, probed, list[0]):
+			have := strings.Join(list, ", ")
+			ret = append(ret, newDiag(DiagTagConflict, tagType, probed, have,
+				fmtCheckError(tagType+" conflicts with the file", probed, have, o.src)))
+		}
+	}
+	// fixATag adds a default atag if it is absent in the source
+	_, hasATag := o.srcTags.byType["atag"]
+
+	switch typ {
+	case "film", "trailer", "teaser", "extra":
+		fill("sdhd", GatherSDHD, false)
+		fill("atag", gatherCompleteATag, !hasATag)
+		fill("stag", GatherSTag, false)
+		fill("ext", formatExtensionFiller(o), false)
+	case "audio":
+		fill("atag", gatherCompleteATag, false)
+		fill("ext", formatExtensionFiller(o), false)
+	case "subtitle":
+		fill("stag", GatherSTag, false)
+		fill("ext", formatExtensionFiller(o), false)
+	case "poster", "poster.gp", "poster.logo":
+		if size, _ := o.GetTag("sizetag"); size != "logo" {
+			fill("sizetag", GatherSizeTag, false)
+		}
+		fill("ext", GatherExtension, false)
+	default:
+		return nil, fmt.Errorf("cannot complete tags of unsupported type %q", typ)
+	}
+	return o.locate(ret), nil
+}
+
+// formatExtensionFiller - accepts any extension of the container format
+func formatExtensionFiller(o *TTagname) func(*ffinfo.File) (string, error) {
+	return func(info *ffinfo.File) (string, error) {
+		ext, err := GatherFormatExtension(info)
+		if err != nil {
+			return "", err
+		}
+		have, _ := o.GetTag("ext")
+		for _, v := range formatExtensions(info.Format.FormatName) {
+			if strings.EqualFold(v, have) {
+				return have, nil
+			}
+		}
+		return ext, nil
+	}
+}
@@ -0,0 +1,102 @@
+package tagname
+
+import (
+	"testing"
+
+	"github.com/malashin/ffinfo"
+)
+
+func testMediaInfo(format string, streams ...ffinfo.Stream) *ffinfo.File {
+	info := &ffinfo.File{Streams: streams}
+	info.Format.FormatName = format
+	return info
+}
+
+func testStream(codecType, codecName string, width, height, channels int, lang string) ffinfo.Stream {
+	s := ffinfo.Stream{CodecType: codecType, CodecName: codecName, Width: width, Height: height, Channels: channels}
+	s.Tags.Language = lang
+	return s
+}
+
+var tableComplete = []struct {
+	input, schema, check string
+	conflicts            []string
+	info                 *ffinfo.File
+}{
+	{input: "name_2018.mp4", schema: "rt",
+		check: "hd_2018_name__ar6e2_sr_xPMkLmsZBFk_film.mp4",
+		info: testMediaInfo("mov,mp4,m4a,3gp,3g2,mj2",
+			testStream("video", "h264", 1920, 1080, 0, ""),
+			testStream("audio", "aac", 0, 0, 6, "rus"),
+			testStream("audio", "aac", 0, 0, 2, "eng"),
+			testStream("subtitle", "mov_text", 0, 0, 0, "rus"),
+		)},
+	{input: "name_2018__sd.trailer", schema: "old",
+		check: "name_2018__sd_ar2.trailer.mpg",
+		info: testMediaInfo("mpeg",
+			testStream("video", "mpeg2video", 720, 576, 0, ""),
+			testStream("audio", "mp2", 0, 0, 2, ""),
+		)},
+	{input: "name_2018__sd_ar6.trailer.mp4", schema: "old",
+		check:     "name_2018__sd_ar6.trailer.mp4",
+		conflicts: []string{"sdhd", "atag"},
+		info: testMediaInfo("mov,mp4,m4a,3gp,3g2,mj2",
+			testStream("video", "h264", 1920, 1080, 0, ""),
+			testStream("audio", "aac", 0, 0, 2, ""),
+		)},
+	{input: "name_2018.jpg", schema: "old",
+		check: "name_2018__1000x1500.jpg",
+		info: testMediaInfo("image2",
+			testStream("video", "mjpeg", 1000, 1500, 0, ""),
+		)},
+}
+
+// TestComplete -
+func TestComplete(t *testing.T) {
+	for _, v := range tableComplete {
+		tn, _ := NewFromString("", v.input, false)
+		if tn == nil {
+			t.Errorf("\n%q\nNewFromString() returned nil", v.input)
+			continue
+		}
+		tn.internalInfo = v.info
+		conflicts, err := tn.Complete()
+		if err != nil {
+			t.Errorf("\n%q\nComplete() error: %v", v.input, err)
+			continue
+		}
+		if len(conflicts) != len(v.conflicts) {
+			t.Errorf("\n%q\nwant conflicts %v, have %v", v.input, v.conflicts, conflicts)
+			continue
+		}
+		for i, d := range conflicts {
+			if d.Code != DiagTagConflict || d.TagType != v.conflicts[i] {
+				t.Errorf("\n%q\nwant conflict of %q, have %+v", v.input, v.conflicts[i], d)
+			}
+		}
+		res, err := tn.ConvertTo(v.schema)
+		if err != nil {
+			t.Errorf("\n%q\nConvertTo() error: %v", v.input, err)
+			continue
+		}
+		if res != v.check {
+			t.Errorf("\nnot equivalent \nin : %q\nres: %q\nchk: %q", v.input, res, v.check)
+		}
+		if len(v.conflicts) == 0 {
+			if err := tn.Check(false); err != nil {
+				t.Errorf("\n%q\nCheck() error: %v", v.input, err)
+			}
+		}
+	}
+}
+
+func TestGatherATag(t *testing.T) {
+	// a single audio stream without a language tag is an error of GatherATag but 'r' for Complete
+	info := testMediaInfo("mpeg", testStream("video", "mpeg2video", 720, 576, 0, ""), testStream("audio", "mp2", 0, 0, 2, ""))
+	if atag, err := GatherATag(info); err == nil {
+		t.Errorf("GatherATag() = %q, error expected", atag)
+	}
+	if atag, err := gatherCompleteATag(info); err != nil || atag != "ar2" {
+		t.Errorf("gatherCompleteATag() = %q, %v, want %q", atag, err, "ar2")
+	}
+}
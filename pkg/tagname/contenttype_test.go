@@ -0,0 +1,112 @@
+package tagname
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/malashin/ffinfo"
+)
+
+var tableContentTypes = []struct {
+	input, typ, old, rt string
+}{
+	{"the_name_2018__hd.extra.mp4", "extra",
+		"the_name_2018__hd_ar2.extra.mp4", "hd_2018_the_name__ar2_extra.mp4"},
+	{"hd_2018_the_name__ar6_extra.mp4", "extra",
+		"the_name_2018__hd_ar6.extra.mp4", "hd_2018_the_name__ar6_extra.mp4"},
+	{"the_name_2018__hd_ae2.audio.ac3", "audio",
+		"the_name_2018__hd_ae2.audio.ac3", "hd_2018_the_name__ae2_audio.ac3"},
+	{"sd_2018_the_name__se_subtitle.srt", "subtitle",
+		"the_name_2018__sd_se.subtitle.srt", "sd_2018_the_name__se_subtitle.srt"},
+	{"the_name_2018__hd.teaser.mp4", "teaser",
+		"the_name_2018__hd.teaser.mp4", "hd_2018_the_name__teaser.mp4"},
+}
+
+// TestContentTypes -
+func TestContentTypes(t *testing.T) {
+	for _, v := range tableContentTypes {
+		tn, err := NewFromString("", v.input, false)
+		if err != nil {
+			t.Errorf("\n%q\nNewFromString() error: %v", v.input, err)
+			continue
+		}
+		if typ, _ := tn.GetType(); typ != v.typ {
+			t.Errorf("\n%q\ntype %q, want %q", v.input, typ, v.typ)
+		}
+		for schema, want := range map[string]string{"old": v.old, "rt": v.rt} {
+			res, err := tn.ConvertTo(schema)
+			if err != nil || res != want {
+				t.Errorf("\n%q\nConvertTo(%q) = %q, %v, want %q", v.input, schema, res, err, want)
+			}
+		}
+	}
+}
+
+// TestContentTypesCheck -
+func TestContentTypesCheck(t *testing.T) {
+	table := []struct {
+		input string
+		codes []string
+	}{
+		{"the_name_2018__hd.audio.ac3", []string{DiagMissingTag}},
+		{"the_name_2018__hd_ar2_q0w0.audio.ac3", []string{DiagInvalidTagType}},
+		{"the_name_2018__hd.subtitle.srt", []string{DiagMissingTag}},
+		{"the_name_2018__hd_ar2_sr.subtitle.srt", []string{DiagInvalidTagType}},
+		{"the_name_2018__hd_sr.subtitle.srt", nil},
+	}
+	for _, v := range table {
+		_, err := NewFromString("", v.input, false)
+		codes := []string(nil)
+		for _, d := range Diagnostics(err) {
+			codes = append(codes, d.Code)
+		}
+		if !reflect.DeepEqual(codes, v.codes) {
+			t.Errorf("\n%q\ncodes %v, want %v (%v)", v.input, codes, v.codes, err)
+		}
+	}
+}
+
+// TestContentTypesDeep -
+func TestContentTypesDeep(t *testing.T) {
+	table := []struct {
+		input string
+		info  *ffinfo.File
+		codes []string
+	}{
+		{"the_name_2018__hd_ar6e2.audio.mka", testMediaInfo("matroska,webm",
+			testStream("audio", "ac3", 0, 0, 6, "rus"),
+			testStream("audio", "ac3", 0, 0, 2, "eng"),
+		), nil},
+		{"the_name_2018__hd_ar6.audio.mp4", testMediaInfo("mov,mp4,m4a,3gp,3g2,mj2",
+			testStream("video", "h264", 1920, 1080, 0, ""),
+			testStream("audio", "aac", 0, 0, 2, "rus"),
+		), []string{DiagUnexpectedStream, DiagAudioMismatch}},
+		{"the_name_2018__hd_sre.subtitle.mkv", testMediaInfo("matroska,webm",
+			testStream("subtitle", "subrip", 0, 0, 0, "rus"),
+		), []string{DiagSubtitleMismatch}},
+		// an .srt file has no language tag
+		{"the_name_2018__hd_sr.subtitle.srt", testMediaInfo("srt",
+			testStream("subtitle", "subrip", 0, 0, 0, ""),
+		), nil},
+		{"the_name_2018__hd_sr.subtitle.mkv", testMediaInfo("matroska,webm",
+			testStream("subtitle", "subrip", 0, 0, 0, "eng"),
+		), []string{DiagSubtitleMismatch}},
+		{"the_name_2018__hd_sr.subtitle.mkv", testMediaInfo("matroska,webm"),
+			[]string{DiagMissingStream}},
+	}
+	for _, v := range table {
+		tn, err := NewFromString("", v.input, false)
+		if err != nil {
+			t.Errorf("\n%q\nNewFromString() error: %v", v.input, err)
+			continue
+		}
+		tn.internalInfo = v.info
+		codes := []string(nil)
+		for _, d := range checkDeep(tn) {
+			codes = append(codes, d.Code)
+		}
+		if !reflect.DeepEqual(codes, v.codes) {
+			t.Errorf("\n%q\ncodes %v, want %v", v.input, codes, v.codes)
+		}
+	}
+}
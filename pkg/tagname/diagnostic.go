@@ -0,0 +1,252 @@
+package tagname
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// TSeverity -
+type TSeverity int
+
+// -
+const (
+	SeverityError TSeverity = iota
+	SeverityWarning
+)
+
+func (o TSeverity) String() string {
+	switch o {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	}
+	return fmt.Sprintf("severity(%d)", int(o))
+}
+
+// MarshalText -
+func (o TSeverity) MarshalText() ([]byte, error) {
+	return []byte(o.String()), nil
+}
+
+// diagnostic codes
+const (
+	DiagMissingTag         = "missing_tag"
+	DiagNonUniqueTag       = "non_unique_tag"
+	DiagInvalidTag         = "invalid_tag"
+	DiagInvalidTagType     = "invalid_tag_type"
+	DiagInvalidTagValue    = "invalid_tag_value"
+	DiagMalformedTag       = "malformed_tag"
+	DiagUnsupportedType    = "unsupported_type"
+	DiagProbeError         = "probe_error"
+	DiagUnsupportedCodec   = "unsupported_codec_type"
+	DiagVideoStreamIndex   = "video_stream_index"
+	DiagSizeMismatch       = "size_mismatch"
+	DiagResolutionMismatch = "resolution_mismatch"
+	DiagSarMismatch        = "sar_mismatch"
+	DiagAudioMismatch      = "audio_mismatch"
+	DiagSubtitleMismatch   = "subtitle_mismatch"
+	DiagDurationMismatch   = "duration_mismatch"
+	DiagStreamDuration     = "stream_duration"
+	DiagUnexpectedStream   = "unexpected_stream"
+	DiagMissingStream      = "missing_stream"
+	DiagParseError         = "parse_error"
+	DiagStaleHash          = "stale_hash"
+	DiagImageFormat        = "image_format"
+	DiagColorModel         = "color_model"
+	DiagProgressive        = "progressive_jpeg"
+	DiagAlphaChannel       = "alpha_channel"
+	DiagFileSize           = "file_size"
+	DiagDPI                = "dpi"
+	DiagExtensionMismatch  = "extension_mismatch"
+	DiagFrameRateMismatch  = "frame_rate_mismatch"
+	DiagFieldOrderMismatch = "field_order_mismatch"
+	DiagPixFmtMismatch     = "pix_fmt_mismatch"
+	DiagBitDepthMismatch   = "bit_depth_mismatch"
+	DiagColorMismatch      = "color_mismatch"
+)
+
+// TSpan - byte span [Start, End) of a tag in the source filename. Start is -1 if unknown.
+type TSpan struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+var noSpan = TSpan{-1, -1}
+
+// IsValid -
+func (o TSpan) IsValid() bool {
+	return o.Start >= 0 && o.End >= o.Start
+}
+
+// TDiagnostic -
+type TDiagnostic struct {
+	Code     string    `json:"code"`
+	Severity TSeverity `json:"severity"`
+	TagType  string    `json:"tag,omitempty"`
+	Expected string    `json:"expected,omitempty"`
+	Actual   string    `json:"actual,omitempty"`
+	Span     TSpan     `json:"span"`
+	Message  string    `json:"message"`
+}
+
+func newDiag(code, tagType, expected, actual, msg string) *TDiagnostic {
+	return &TDiagnostic{
+		Code:     code,
+		Severity: SeverityError,
+		TagType:  tagType,
+		Expected: expected,
+		Actual:   actual,
+		Span:     noSpan,
+		Message:  msg,
+	}
+}
+
+// Error -
+func (o *TDiagnostic) Error() string {
+	return o.Message
+}
+
+// TDiagnostics -
+type TDiagnostics []*TDiagnostic
+
+// Error - renders the list the same way the checker errors were rendered
+func (o TDiagnostics) Error() string {
+	switch len(o) {
+	case 0:
+		return ""
+	case 1:
+		return o[0].Message
+	}
+	list := make([]string, 0, len(o))
+	for _, d := range o {
+		list = append(list, d.Message)
+	}
+	return fmt.Sprintf("some error(s):\n        %v", strings.Join(list, "\n        "))
+}
+
+// Errors - returns diagnostics of the error severity only
+func (o TDiagnostics) Errors() TDiagnostics {
+	var ret TDiagnostics
+	for _, d := range o {
+		if d.Severity == SeverityError {
+			ret = append(ret, d)
+		}
+	}
+	return ret
+}
+
+// Err - returns nil if there are no diagnostics of the error severity
+func (o TDiagnostics) Err() error {
+	ret := o.Errors()
+	if len(ret) == 0 {
+		return nil
+	}
+	return ret
+}
+
+// Filter - returns diagnostics whose code is one of codes
+func (o TDiagnostics) Filter(codes ...string) TDiagnostics {
+	var ret TDiagnostics
+	for _, d := range o {
+		for _, code := range codes {
+			if d.Code == code {
+				ret = append(ret, d)
+				break
+			}
+		}
+	}
+	return ret
+}
+
+// Exclude - returns diagnostics whose code is not one of codes
+func (o TDiagnostics) Exclude(codes ...string) TDiagnostics {
+	var ret TDiagnostics
+	for _, d := range o {
+		if len(TDiagnostics{d}.Filter(codes...)) == 0 {
+			ret = append(ret, d)
+		}
+	}
+	return ret
+}
+
+// Diagnostics - extracts diagnostics from an error returned by a check.
+// Any other non-nil error is wrapped into a single diagnostic.
+func Diagnostics(err error) TDiagnostics {
+	if err == nil {
+		return nil
+	}
+	var list TDiagnostics
+	if errors.As(err, &list) {
+		return list
+	}
+	var diag *TDiagnostic
+	if errors.As(err, &diag) {
+		return TDiagnostics{diag}
+	}
+	if list := SchemaErrors(err); len(list) > 0 {
+		ret := TDiagnostics{}
+		for _, se := range list {
+			d := newDiag(DiagParseError, "", strings.Join(se.Expected, "|"), se.Char(),
+				fmt.Sprintf("%q %v", se.Schema, se))
+			d.Span = TSpan{se.Offset, se.Offset + len(se.Char())}
+			ret = append(ret, d)
+		}
+		return ret
+	}
+	return TDiagnostics{newDiag("", "", "", "", err.Error())}
+}
+
+type tTagKey struct {
+	typ, val string
+}
+
+func (o *TTags) addSpan(typ, val string, span TSpan) {
+	if o.spans == nil {
+		o.spans = map[tTagKey]TSpan{}
+	}
+	key := tTagKey{typ, val}
+	if _, ok := o.spans[key]; ok {
+		return
+	}
+	o.spans[key] = span
+}
+
+// Span - returns a position of the tag in the source string
+func (o *TTags) Span(typ, val string) TSpan {
+	if o == nil {
+		return noSpan
+	}
+	if span, ok := o.spans[tTagKey{typ, val}]; ok {
+		return span
+	}
+	// translated tags keep either a type or a value of the source one
+	keys := make([]tTagKey, 0, len(o.spans))
+	for key := range o.spans {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return o.spans[keys[i]].Start < o.spans[keys[j]].Start })
+	for _, key := range keys {
+		if val != "" && strings.EqualFold(key.val, val) {
+			return o.spans[key]
+		}
+	}
+	for _, key := range keys {
+		if key.typ == typ {
+			return o.spans[key]
+		}
+	}
+	return noSpan
+}
+
+func (o *TTagname) locate(list TDiagnostics) TDiagnostics {
+	for _, d := range list {
+		if d.Span.IsValid() || d.TagType == "" {
+			continue
+		}
+		d.Span = o.srcTags.Span(d.TagType, d.Actual)
+	}
+	return list
+}
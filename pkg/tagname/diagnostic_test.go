@@ -0,0 +1,59 @@
+package tagname
+
+import (
+	"testing"
+)
+
+var tableDiagnostics = []struct {
+	input string
+	diags []tdiag
+}{
+	{input: "sd_2018_the_name__ab_trailer.mp4",
+		diags: []tdiag{
+			{code: DiagMalformedTag, typ: "ERR_atag", span: "ab"},
+		},
+	},
+	{input: "the_name_2018__hd_ab_17_q0w0_q0w1.trailer.mp4",
+		diags: []tdiag{
+			{code: DiagMalformedTag, typ: "ERR_agetag", span: "17"},
+			{code: DiagMalformedTag, typ: "ERR_atag", span: "ab"},
+			{code: DiagNonUniqueTag, typ: "qtag", span: "q0w0"},
+		},
+	},
+	{input: "the_name_2018__hd_vfoo_foo.mp4",
+		diags: []tdiag{
+			{code: DiagInvalidTagType, typ: "UNKNOWN_TAG", span: "foo"},
+		},
+	},
+	{input: "the_name_2018__hd_16_q0w0.trailer.mp4"},
+}
+
+type tdiag struct {
+	code, typ, span string
+}
+
+// TestDiagnostics -
+func TestDiagnostics(t *testing.T) {
+	for _, v := range tableDiagnostics {
+		_, err := NewFromString("", v.input, false)
+		list := Diagnostics(err)
+		if len(list) != len(v.diags) {
+			t.Errorf("\n%q\nwant %v diagnostic(s), have %v: %v", v.input, len(v.diags), len(list), err)
+			continue
+		}
+		for i, d := range list {
+			want := v.diags[i]
+			span := ""
+			if d.Span.IsValid() {
+				span = v.input[d.Span.Start:d.Span.End]
+			}
+			have := tdiag{code: d.Code, typ: d.TagType, span: span}
+			if have != want {
+				t.Errorf("\n%q\nwant %+v\nhave %+v", v.input, want, have)
+			}
+		}
+		if len(list) > 0 && list.Error() != err.Error() {
+			t.Errorf("\n%q\nrendering differs: %q != %q", v.input, list.Error(), err.Error())
+		}
+	}
+}
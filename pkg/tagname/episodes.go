@@ -0,0 +1,218 @@
+package tagname
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// An 'exx' tag is a single episode ('01', '002b'), a range ('01-03') or a list
+// of them ('01+03', '01-02+05'). An 'a'/'b' suffix marks a part of the episode.
+
+var reEpisodeItem = regexp.MustCompile(`^(\d{2,3})([ab]?)$`)
+
+type tEpisodeItem struct {
+	num  int
+	part string
+}
+
+type tEpisodeRange struct {
+	from, to tEpisodeItem
+}
+
+// parseExx - returns ranges of the exx tag and the width of its numbers
+func parseExx(val string) ([]tEpisodeRange, int, error) {
+	if val == "" {
+		return nil, 0, fmt.Errorf("empty episode tag")
+	}
+	ret := []tEpisodeRange{}
+	width := 0
+	item := func(s string) (tEpisodeItem, error) {
+		m := reEpisodeItem.FindStringSubmatch(s)
+		if m == nil {
+			return tEpisodeItem{}, fmt.Errorf("invalid episode %q in %q", s, val)
+		}
+		if len(m[1]) > width {
+			width = len(m[1])
+		}
+		n, _ := strconv.Atoi(m[1])
+		return tEpisodeItem{n, m[2]}, nil
+	}
+	for _, s := range strings.Split(val, "+") {
+		from, to, isRange := strings.Cut(s, "-")
+		a, err := item(from)
+		if err != nil {
+			return nil, 0, err
+		}
+		b := a
+		if isRange {
+			b, err = item(to)
+			if err != nil {
+				return nil, 0, err
+			}
+			if b.num < a.num || (b.num == a.num && b.part <= a.part) {
+				return nil, 0, fmt.Errorf("invalid episode range %q in %q", s, val)
+			}
+		}
+		ret = append(ret, tEpisodeRange{a, b})
+	}
+	return ret, width, nil
+}
+
+// episodeNumbers - expands the exx tag into a sorted list of unique episode numbers
+func episodeNumbers(val string) ([]int, error) {
+	ranges, _, err := parseExx(val)
+	if err != nil {
+		return nil, err
+	}
+	set := map[int]bool{}
+	for _, r := range ranges {
+		for n := r.from.num; n <= r.to.num; n++ {
+			set[n] = true
+		}
+	}
+	ret := []int{}
+	for n := range set {
+		ret = append(ret, n)
+	}
+	sort.Ints(ret)
+	return ret, nil
+}
+
+// normalizeExx - renders episodes sorted with consecutive ones joined into ranges
+// ('03+01+02' -> '01-03'). Tags with parts ('01a-01b') are left as they are.
+func normalizeExx(val string) string {
+	ranges, width, err := parseExx(val)
+	if err != nil || len(ranges) == 1 && ranges[0].from == ranges[0].to {
+		return val
+	}
+	for _, r := range ranges {
+		if r.from.part != "" || r.to.part != "" {
+			return val
+		}
+	}
+	nums, _ := episodeNumbers(val)
+	list := []string{}
+	for i := 0; i < len(nums); {
+		j := i
+		for j+1 < len(nums) && nums[j+1] == nums[j]+1 {
+			j++
+		}
+		s := fmt.Sprintf("%0*d", width, nums[i])
+		if j > i {
+			s += fmt.Sprintf("-%0*d", width, nums[j])
+		}
+		list = append(list, s)
+		i = j + 1
+	}
+	return strings.Join(list, "+")
+}
+
+// TEpisode - an episode or a part of it ('a', 'b'), the part is empty for a whole episode
+type TEpisode struct {
+	Num  int
+	Part string
+}
+
+// String - 'e05', 'e05a'
+func (o TEpisode) String() string {
+	return fmt.Sprintf("e%02d%v", o.Num, o.Part)
+}
+
+// episodeParts - expands the exx tag into a sorted list of unique episodes and their parts.
+// The ends of a range keep their parts ('04b-06a' -> 04b, 05, 06a).
+func episodeParts(val string) ([]TEpisode, error) {
+	ranges, _, err := parseExx(val)
+	if err != nil {
+		return nil, err
+	}
+	set := map[TEpisode]bool{}
+	for _, r := range ranges {
+		if r.from.num == r.to.num {
+			set[TEpisode{r.from.num, r.from.part}] = true
+			if r.to.part != r.from.part {
+				next := byte('a')
+				if r.from.part != "" {
+					next = r.from.part[0] + 1
+				}
+				for c := next; c <= r.to.part[0]; c++ {
+					set[TEpisode{r.from.num, string(c)}] = true
+				}
+			}
+			continue
+		}
+		set[TEpisode{r.from.num, r.from.part}] = true
+		for n := r.from.num + 1; n < r.to.num; n++ {
+			set[TEpisode{n, ""}] = true
+		}
+		set[TEpisode{r.to.num, r.to.part}] = true
+	}
+	ret := make([]TEpisode, 0, len(set))
+	for e := range set {
+		ret = append(ret, e)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Num != ret[j].Num {
+			return ret[i].Num < ret[j].Num
+		}
+		return ret[i].Part < ret[j].Part
+	})
+	return ret, nil
+}
+
+// seasonExx - the season number (-1 for 'sxx') and the exx tag, empty for the whole season
+func (o *TTagname) seasonExx() (int, string, error) {
+	if err := o.State(); err != nil {
+		return 0, "", err
+	}
+	sxx, err := o.GetTag("sxx")
+	if err != nil {
+		return 0, "", err
+	}
+	season := -1
+	if !strings.EqualFold(sxx, "sxx") {
+		season, err = strconv.Atoi(sxx[1:])
+		if err != nil {
+			return 0, "", fmt.Errorf("invalid season tag %q", sxx)
+		}
+	}
+	list := o.GetTags("exx")
+	if len(list) > 1 {
+		return 0, "", fmt.Errorf("too many 'exx' tags: %v", list)
+	}
+	if len(list) == 0 {
+		return season, "", nil
+	}
+	return season, list[0], nil
+}
+
+// Episodes - returns the season number and the episode numbers of the tagname.
+// The season is -1 for an unknown season ('sxx'). An episode range is expanded,
+// parts of an episode ('01a', '01b') are reported as the episode itself.
+// No episodes means the whole season.
+func (o *TTagname) Episodes() (int, []int, error) {
+	season, exx, err := o.seasonExx()
+	if err != nil || exx == "" {
+		return season, nil, err
+	}
+	episodes, err := episodeNumbers(exx)
+	if err != nil {
+		return 0, nil, err
+	}
+	return season, episodes, nil
+}
+
+// EpisodeParts - the same as Episodes but parts of an episode ('01a', '01b') are reported separately
+func (o *TTagname) EpisodeParts() (int, []TEpisode, error) {
+	season, exx, err := o.seasonExx()
+	if err != nil || exx == "" {
+		return season, nil, err
+	}
+	episodes, err := episodeParts(exx)
+	if err != nil {
+		return 0, nil, err
+	}
+	return season, episodes, nil
+}
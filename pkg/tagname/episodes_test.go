@@ -0,0 +1,151 @@
+package tagname
+
+import (
+	"reflect"
+	"testing"
+)
+
+var tableEpisodes = []struct {
+	input    string
+	rt       string
+	old      string
+	season   int
+	episodes []int
+}{
+	{input: "b_s01_01-02_2000__hd",
+		rt:     "hd_2000_b_s01_01-02__ar6_xSw0GP0CpBF_film",
+		old:    "b_s01_01-02_2000__hd_ar6",
+		season: 1, episodes: []int{1, 2}},
+	{input: "sd_2000_b_s01_05+01+02__ar2_trailer",
+		rt:     "sd_2000_b_s01_01-02+05__ar2_trailer",
+		old:    "b_s01_01-02+05_2000__sd_ar2.trailer",
+		season: 1, episodes: []int{1, 2, 5}},
+	{input: "the_name_s02_009-011_a_subname_2018__hd_q0w0_ar6",
+		rt:     "hd_2018_the_name_s02_009-011_a_subname__q0w0_ar6_xpQLqIf1Oor_film",
+		old:    "the_name_s02_009-011_a_subname_2018__hd_q0w0_ar6",
+		season: 2, episodes: []int{9, 10, 11}},
+	{input: "b_s01_01a-01b_2000__hd",
+		rt:     "hd_2000_b_s01_01a-01b__ar6_xSw0GP0CpBF_film",
+		old:    "b_s01_01a-01b_2000__hd_ar6",
+		season: 1, episodes: []int{1}},
+	{input: "b_sXX_07_2000__hd",
+		rt:     "hd_2000_b_sXX_07__ar6_xrcCq14QK8B_film",
+		old:    "b_sXX_07_2000__hd_ar6",
+		season: -1, episodes: []int{7}},
+	{input: "b_s03_2000__hd",
+		rt:     "hd_2000_b_s03__ar6_xXq5c1P7oRX_film",
+		old:    "b_s03_2000__hd_ar6",
+		season: 3, episodes: nil},
+}
+
+func TestEpisodes(t *testing.T) {
+	for _, v := range tableEpisodes {
+		tn, err := NewFromString("", v.input, false)
+		if err != nil {
+			t.Errorf("\n%q\nNewFromString() error:\n%v", v.input, err)
+			continue
+		}
+		season, episodes, err := tn.Episodes()
+		if err != nil {
+			t.Errorf("\n%q\nEpisodes() error: %v", v.input, err)
+			continue
+		}
+		if season != v.season || !reflect.DeepEqual(episodes, v.episodes) {
+			t.Errorf("\n%q\nhave: %v %v\nwant: %v %v", v.input, season, episodes, v.season, v.episodes)
+		}
+		for _, conv := range []struct{ schema, check string }{{"rt", v.rt}, {"old", v.old}} {
+			res, err := tn.ConvertTo(conv.schema)
+			if err != nil {
+				t.Errorf("\n%q\nConvertTo(%q) error: %v", v.input, conv.schema, err)
+				continue
+			}
+			if res != conv.check {
+				t.Errorf("\n%q\nConvertTo(%q)\nhave: %q\nwant: %q", v.input, conv.schema, res, conv.check)
+				continue
+			}
+			// round trip
+			tn2, err := NewFromString("", res, false)
+			if err != nil {
+				t.Errorf("\n%q\nround trip error: %v", res, err)
+				continue
+			}
+			res2, _ := tn2.ConvertTo(conv.schema)
+			if res2 != res {
+				t.Errorf("\nround trip is not stable\nhave: %q\nwant: %q", res2, res)
+			}
+		}
+	}
+}
+
+func TestEpisodeParts(t *testing.T) {
+	table := []struct {
+		input    string
+		episodes []TEpisode
+	}{
+		{"b_s01_01-02_2000__hd", []TEpisode{{1, ""}, {2, ""}}},
+		{"b_s01_01a-01b_2000__hd", []TEpisode{{1, "a"}, {1, "b"}}},
+		{"b_s01_07_2000__hd", []TEpisode{{7, ""}}},
+		{"b_s01_05b_2000__hd", []TEpisode{{5, "b"}}},
+		{"b_s01_04b-06a_2000__hd", []TEpisode{{4, "b"}, {5, ""}, {6, "a"}}},
+		{"b_s03_2000__hd", nil},
+	}
+	for _, v := range table {
+		tn, err := NewFromString("", v.input, false)
+		if err != nil {
+			t.Errorf("\n%q\nNewFromString() error:\n%v", v.input, err)
+			continue
+		}
+		_, episodes, err := tn.EpisodeParts()
+		if err != nil || !reflect.DeepEqual(episodes, v.episodes) {
+			t.Errorf("\n%q\nhave: %v %v\nwant: %v", v.input, episodes, err, v.episodes)
+		}
+	}
+}
+
+func TestEpisodesHash(t *testing.T) {
+	// all episodes of the season share the hash
+	have := map[string]bool{}
+	for _, v := range []string{"b_s01_01_2000__hd", "b_s01_01-02_2000__hd", "b_s01_03+05_2000__hd"} {
+		tn, err := NewFromString("", v, false)
+		if err != nil {
+			t.Fatalf("\n%q\nNewFromString() error:\n%v", v, err)
+		}
+		tags, err := TranslateTags(tn.tags, rtNormalSchema.MarshallFilter)
+		if err != nil {
+			t.Fatalf("\n%q\nTranslateTags() error:\n%v", v, err)
+		}
+		hash, _ := tags.GetTag("hashtag")
+		have[hash] = true
+	}
+	if len(have) != 1 {
+		t.Errorf("want one hash, have %v", have)
+	}
+}
+
+func TestEpisodesIncorrect(t *testing.T) {
+	for _, v := range []string{
+		"b_s01_01-_2000__hd",
+		"b_s01_02-01_2000__hd",
+		"b_s01_01+_2000__hd",
+	} {
+		_, err := NewFromString("", v, false)
+		if err == nil {
+			t.Errorf("\n%q\nhas no error", v)
+		}
+	}
+}
+
+func TestNormalizeSnen(t *testing.T) {
+	for _, v := range []struct{ in, want string }{
+		{"s01e05", "s01_05"},
+		{"s1e1-2", "s01_01-02"},
+		{"S01E01E02", "s01_01-02"},
+		{"s01e01-e03", "s01_01-03"},
+		{"s01e01+e05", "s01_01+05"},
+	} {
+		list, _ := normalizeSnen(tokenize(v.in))
+		if have := untokenize(list); have != v.want {
+			t.Errorf("\n%q\nhave: %q\nwant: %q", v.in, have, v.want)
+		}
+	}
+}
@@ -14,6 +14,11 @@ func (o *TTagname) GatherExtension() (string, error) {
 	return gatherWrapper(o, GatherExtension)
 }
 
+// GatherFormatExtension -
+func (o *TTagname) GatherFormatExtension() (string, error) {
+	return gatherWrapper(o, GatherFormatExtension)
+}
+
 // GatherSizeTag -
 func (o *TTagname) GatherSizeTag() (string, error) {
 	return gatherWrapper(o, GatherSizeTag)
@@ -60,6 +65,41 @@ func GatherExtension(info *ffinfo.File) (string, error) {
 	return codecName, nil
 }
 
+// formatExtensions - returns extensions of the container format, the preferred one goes first
+func formatExtensions(formatName string) []string {
+	switch formatName {
+	case "mov,mp4,m4a,3gp,3g2,mj2":
+		return []string{".mp4", ".mov", ".m4a", ".m4v", ".3gp", ".3g2", ".mj2"}
+	case "mpeg":
+		return []string{".mpg", ".mpeg", ".vob"}
+	case "mpegts":
+		return []string{".ts", ".m2ts", ".mts"}
+	case "matroska,webm":
+		return []string{".mkv", ".webm", ".mka"}
+	case "mxf":
+		return []string{".mxf"}
+	case "avi":
+		return []string{".avi"}
+	case "wav":
+		return []string{".wav"}
+	case "image2", "jpeg_pipe", "png_pipe", "bmp_pipe", "tiff_pipe":
+		return nil
+	}
+	return nil
+}
+
+// GatherFormatExtension - returns an extension of the container format
+func GatherFormatExtension(info *ffinfo.File) (string, error) {
+	if info == nil {
+		return "", errorFFInfoIsNil
+	}
+	list := formatExtensions(info.Format.FormatName)
+	if len(list) == 0 {
+		return "", fmt.Errorf("unsupported container format %q", info.Format.FormatName)
+	}
+	return list[0], nil
+}
+
 // GatherSizeTag -
 func GatherSizeTag(info *ffinfo.File) (string, error) {
 	if info == nil {
@@ -75,6 +115,16 @@ func GatherSizeTag(info *ffinfo.File) (string, error) {
 
 // GatherATag -
 func GatherATag(info *ffinfo.File) (string, error) {
+	return gatherATag(info, false)
+}
+
+// gatherCompleteATag - the atag Complete fills in, a single audio stream of any language is 'r'
+// as the language of a single track is not checked (see checkDeep)
+func gatherCompleteATag(info *ffinfo.File) (string, error) {
+	return gatherATag(info, true)
+}
+
+func gatherATag(info *ffinfo.File, anySingleLanguage bool) (string, error) {
 	if info == nil {
 		return "", errorFFInfoIsNil
 	}
@@ -97,7 +147,7 @@ func GatherATag(info *ffinfo.File) (string, error) {
 	if len(audio) == 0 {
 		return "", nil
 	}
-	if len(audio) == 1 && audio[0].Language != "---" {
+	if len(audio) == 1 && (anySingleLanguage || audio[0].Language != "---") {
 		audio[0].Language = "rus"
 	}
 
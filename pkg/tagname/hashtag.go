@@ -0,0 +1,62 @@
+package tagname
+
+import "fmt"
+
+// HashKey - a title key (name_sxx_year_sdhd_comment) the hashtag is generated from
+func (o *TTagname) HashKey() string {
+	if o.State() != nil {
+		return ""
+	}
+	return hashKey(o.tags)
+}
+
+// TitleKey - the HashKey without the format (name_sxx_year__comment). Every deliverable of a title
+// shares it: the film, trailers, posters of any format and gp posters that have no sdhd tag.
+func (o *TTagname) TitleKey() string {
+	if o.State() != nil {
+		return ""
+	}
+	return titleKey(o.tags, "")
+}
+
+// HashTags - the hashtag of the source (empty if it is absent) and the one generated by the current tags
+func (o *TTagname) HashTags() (have, want string) {
+	if o.State() != nil {
+		return "", ""
+	}
+	have, _ = o.srcTags.GetTag("hashtag")
+	return have, genHashTag(o.tags)
+}
+
+// VerifyHash - returns a DiagStaleHash diagnostic if the hashtag of the source does not match
+// the tags (e.g. after a manual edit of the name). A tagname without a hashtag is not verified.
+func (o *TTagname) VerifyHash() error {
+	if err := o.State(); err != nil {
+		return err
+	}
+	have, want := o.HashTags()
+	if have == "" || have == want {
+		return nil
+	}
+	d := newDiag(DiagStaleHash, "hashtag", want, have,
+		fmt.Sprintf("stale hashtag %v (want %v for %q)", have, want, o.HashKey()))
+	d.Span = o.srcTags.Span("hashtag", have)
+	return d
+}
+
+// Rehash - returns the source filename with the stale hashtag replaced. It returns the filename
+// as it is if the hashtag is absent or valid.
+func (o *TTagname) Rehash() (string, error) {
+	if err := o.State(); err != nil {
+		return "", err
+	}
+	have, want := o.HashTags()
+	if have == "" || have == want {
+		return o.src, nil
+	}
+	span := o.srcTags.Span("hashtag", have)
+	if !span.IsValid() || span.End > len(o.src) || o.src[span.Start:span.End] != have {
+		return "", fmt.Errorf("cannot locate hashtag %v in %q", have, o.src)
+	}
+	return o.src[:span.Start] + want + o.src[span.End:], nil
+}
@@ -0,0 +1,65 @@
+package tagname
+
+import (
+	"testing"
+)
+
+// TestVerifyHash -
+func TestVerifyHash(t *testing.T) {
+	table := []struct {
+		input string
+		stale bool
+		check string
+	}{
+		{"hd_2019_the_movie__ar6e2_x1GF40gNCfD_film.mp4", false, "hd_2019_the_movie__ar6e2_x1GF40gNCfD_film.mp4"},
+		{"hd_2019_the_movie_2__ar6e2_x1GF40gNCfD_film.mp4", true, "hd_2019_the_movie_2__ar6e2_x1gG3zpbS3I_film.mp4"},
+		{"sd_2019_the_movie__ar2_x1GF40gNCfD_film.mp4", true, "sd_2019_the_movie__ar2_xIAgPq4op0Q_film.mp4"},
+		{"the_movie_2019__hd_ar6e2.mp4", false, "the_movie_2019__hd_ar6e2.mp4"},
+	}
+	for _, v := range table {
+		tn, err := NewFromString("", v.input, false)
+		if tn == nil {
+			t.Errorf("\n%q\nNewFromString() error: %v", v.input, err)
+			continue
+		}
+		err = tn.VerifyHash()
+		if (err != nil) != v.stale {
+			t.Errorf("\n%q\nVerifyHash() = %v, want stale = %v", v.input, err, v.stale)
+		}
+		if d := Diagnostics(err); v.stale && (len(d) != 1 || d[0].Code != DiagStaleHash ||
+			v.input[d[0].Span.Start:d[0].Span.End] != d[0].Actual) {
+			t.Errorf("\n%q\ndiagnostics %+v", v.input, d)
+		}
+		res, err := tn.Rehash()
+		if err != nil || res != v.check {
+			t.Errorf("\n%q\nRehash() = %q, %v, want %q", v.input, res, err, v.check)
+		}
+		if tn2, err := NewFromString("", res, false); err != nil || tn2.VerifyHash() != nil {
+			t.Errorf("\n%q\nrehashed %q does not verify: %v", v.input, res, err)
+		}
+	}
+}
+
+// TestTitleKey -
+func TestTitleKey(t *testing.T) {
+	table := []struct {
+		input      string
+		key, title string
+	}{
+		{"the_movie_2019__hd_ar6e2.mp4", "the_movie__2019_hd_", "the_movie__2019__"},
+		{"the_movie_2019__sd_ar2.trailer.mpg", "the_movie__2019_sd_", "the_movie__2019__"},
+		{"the_movie_2019__hd_1000x1500.poster.jpg", "the_movie__2019_hd_", "the_movie__2019__"},
+		{"the_movie_2019_600x800.jpg", "the_movie__2019__", "the_movie__2019__"},
+		{"the_movie_s01_2019__hd.mp4", "the_movie_s01_2019_hd_", "the_movie_s01_2019__"},
+	}
+	for _, v := range table {
+		tn, err := NewFromString("", v.input, false)
+		if tn == nil {
+			t.Errorf("\n%q\nNewFromString() error: %v", v.input, err)
+			continue
+		}
+		if key, title := tn.HashKey(), tn.TitleKey(); key != v.key || title != v.title {
+			t.Errorf("\n%q\nHashKey() = %q, TitleKey() = %q, want %q, %q", v.input, key, title, v.key, v.title)
+		}
+	}
+}
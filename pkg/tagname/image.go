@@ -0,0 +1,397 @@
+package tagname
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/jpeg" // registers the jpeg decoder
+	_ "image/png"  // registers the png decoder
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// TImageInfo - properties of a poster image read from its content
+type TImageInfo struct {
+	Format      string // 'jpeg' or 'png' as detected by content
+	Width       int
+	Height      int
+	ColorModel  string // 'rgb', 'cmyk', 'gray' or 'indexed'
+	Progressive bool   // a progressive jpeg
+	Alpha       bool   // the image has an alpha channel or a transparent palette entry
+	FileSize    int64
+	DPI         TResolution // embedded pixel density, zero if absent
+}
+
+// InspectImage - reads the image properties by decoding the header of the file
+func InspectImage(path string) (*TImageInfo, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return inspectImage(data)
+}
+
+func inspectImage(data []byte) (*TImageInfo, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode image: %v", err)
+	}
+	ret := &TImageInfo{Format: format, Width: config.Width, Height: config.Height, FileSize: int64(len(data))}
+	switch format {
+	default:
+		return nil, fmt.Errorf("unsupported image format %q", format)
+	case "jpeg":
+		switch config.ColorModel {
+		case color.GrayModel:
+			ret.ColorModel = "gray"
+		case color.CMYKModel:
+			ret.ColorModel = "cmyk"
+		default:
+			ret.ColorModel = "rgb"
+		}
+		err = scanJpegMarkers(data, ret)
+	case "png":
+		err = scanPngChunks(data, ret)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// scanJpegMarkers - reads the frame type and the density from the segments before the scan data.
+// The JFIF density is preferred to the EXIF one if both are present.
+func scanJpegMarkers(data []byte, info *TImageInfo) error {
+	exifDPI := TResolution{}
+	defer func() {
+		if info.DPI == (TResolution{}) {
+			info.DPI = exifDPI
+		}
+	}()
+	pos := 2 // SOI
+	for pos+4 <= len(data) {
+		if data[pos] != 0xff {
+			return fmt.Errorf("jpeg: invalid marker at %v", pos)
+		}
+		marker := data[pos+1]
+		if marker == 0xff { // fill byte
+			pos++
+			continue
+		}
+		if marker == 0x01 || 0xd0 <= marker && marker <= 0xd8 { // segments without a length
+			pos += 2
+			continue
+		}
+		size := int(binary.BigEndian.Uint16(data[pos+2:]))
+		if size < 2 || pos+2+size > len(data) {
+			return fmt.Errorf("jpeg: truncated segment at %v", pos)
+		}
+		seg := data[pos+4 : pos+2+size]
+		switch marker {
+		case 0xc2, 0xc6, 0xca, 0xce: // SOF2, SOF6, SOF10, SOF14
+			info.Progressive = true
+		case 0xe0: // APP0
+			if len(seg) >= 12 && string(seg[:5]) == "JFIF\x00" {
+				x := float64(binary.BigEndian.Uint16(seg[8:]))
+				y := float64(binary.BigEndian.Uint16(seg[10:]))
+				switch seg[7] {
+				case 1: // dots per inch
+					info.DPI = TResolution{int(x), int(y)}
+				case 2: // dots per cm
+					info.DPI = TResolution{int(math.Round(x * 2.54)), int(math.Round(y * 2.54))}
+				}
+			}
+		case 0xe1: // APP1
+			if len(seg) >= 6 && string(seg[:6]) == "Exif\x00\x00" {
+				exifDPI = exifResolution(seg[6:])
+			}
+		case 0xda, 0xd9: // SOS, EOI
+			return nil
+		}
+		pos += 2 + size
+	}
+	return nil
+}
+
+// exifResolution - reads XResolution, YResolution and ResolutionUnit of IFD0 of the TIFF data,
+// zero if they are absent or malformed
+func exifResolution(tiff []byte) TResolution {
+	if len(tiff) < 8 {
+		return TResolution{}
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	default:
+		return TResolution{}
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	}
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return TResolution{}
+	}
+	rational := func(entry []byte) float64 {
+		offset := int(order.Uint32(entry[8:]))
+		if order.Uint16(entry[2:]) != 5 || offset < 0 || offset+8 > len(tiff) { // RATIONAL
+			return 0
+		}
+		den := order.Uint32(tiff[offset+4:])
+		if den == 0 {
+			return 0
+		}
+		return float64(order.Uint32(tiff[offset:])) / float64(den)
+	}
+	x, y, unit := 0.0, 0.0, uint16(2) // inches by default
+	count := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < count; i++ {
+		pos := ifd + 2 + i*12
+		if pos+12 > len(tiff) {
+			break
+		}
+		entry := tiff[pos : pos+12]
+		switch order.Uint16(entry) {
+		case 0x011a:
+			x = rational(entry)
+		case 0x011b:
+			y = rational(entry)
+		case 0x0128:
+			unit = order.Uint16(entry[8:])
+		}
+	}
+	switch unit {
+	case 2: // inches
+		return TResolution{int(math.Round(x)), int(math.Round(y))}
+	case 3: // centimetres
+		return TResolution{int(math.Round(x * 2.54)), int(math.Round(y * 2.54))}
+	}
+	return TResolution{}
+}
+
+// scanPngChunks - reads the colour type, the transparency and the physical density from the chunks before the image data
+func scanPngChunks(data []byte, info *TImageInfo) error {
+	pos := 8 // signature
+	for pos+8 <= len(data) {
+		size := int(binary.BigEndian.Uint32(data[pos:]))
+		typ := string(data[pos+4 : pos+8])
+		if size < 0 || pos+12+size > len(data) {
+			return fmt.Errorf("png: truncated chunk %q at %v", typ, pos)
+		}
+		chunk := data[pos+8 : pos+8+size]
+		switch typ {
+		case "IHDR":
+			if len(chunk) < 13 {
+				return fmt.Errorf("png: invalid IHDR chunk")
+			}
+			switch chunk[9] {
+			case 0:
+				info.ColorModel = "gray"
+			case 2:
+				info.ColorModel = "rgb"
+			case 3:
+				info.ColorModel = "indexed"
+			case 4:
+				info.ColorModel = "gray"
+				info.Alpha = true
+			case 6:
+				info.ColorModel = "rgb"
+				info.Alpha = true
+			}
+		case "tRNS":
+			info.Alpha = true
+		case "pHYs":
+			if len(chunk) >= 9 && chunk[8] == 1 { // pixels per metre
+				x := float64(binary.BigEndian.Uint32(chunk))
+				y := float64(binary.BigEndian.Uint32(chunk[4:]))
+				info.DPI = TResolution{int(math.Round(x * 0.0254)), int(math.Round(y * 0.0254))}
+			}
+		case "IDAT", "IEND":
+			return nil
+		}
+		pos += 12 + size
+	}
+	return nil
+}
+
+// tImageRules - platform limits of a poster image, zero values do not limit
+type tImageRules struct {
+	Formats     []string // allowed formats ('jpeg', 'png')
+	ColorModels []string // allowed colour models ('rgb', 'cmyk', 'gray', 'indexed')
+	Progressive string   // 'yes' - required, 'no' - forbidden
+	Alpha       string   // 'yes' - required, 'no' - forbidden
+	MaxFileSize int64    // bytes
+	DPI         []int    // allowed densities
+}
+
+// updateImageRules - limits of in2 override the ones of in1
+func updateImageRules(in1, in2 tImageRules) tImageRules {
+	ret := in1
+	if len(in2.Formats) > 0 {
+		ret.Formats = in2.Formats
+	}
+	if len(in2.ColorModels) > 0 {
+		ret.ColorModels = in2.ColorModels
+	}
+	if in2.Progressive != "" {
+		ret.Progressive = in2.Progressive
+	}
+	if in2.Alpha != "" {
+		ret.Alpha = in2.Alpha
+	}
+	if in2.MaxFileSize > 0 {
+		ret.MaxFileSize = in2.MaxFileSize
+	}
+	if len(in2.DPI) > 0 {
+		ret.DPI = in2.DPI
+	}
+	return ret
+}
+
+// set - sets a limit by a check profile key
+func (o *tImageRules) set(key, val string) error {
+	switch key {
+	default:
+		return fmt.Errorf("unknown key %q", key)
+	case "formats":
+		o.Formats = strings.Fields(val)
+	case "colormodels":
+		o.ColorModels = strings.Fields(val)
+	case "progressive", "alpha":
+		if val != "yes" && val != "no" {
+			return fmt.Errorf("%v: want 'yes' or 'no', have %q", key, val)
+		}
+		if key == "progressive" {
+			o.Progressive = val
+		} else {
+			o.Alpha = val
+		}
+	case "maxsize":
+		size, err := parseFileSize(val)
+		if err != nil {
+			return fmt.Errorf("%v: %v", key, err)
+		}
+		o.MaxFileSize = size
+	case "dpi":
+		o.DPI = nil
+		for _, s := range strings.Fields(val) {
+			dpi, err := strconv.Atoi(s)
+			if err != nil || dpi <= 0 {
+				return fmt.Errorf("%v: invalid value %q", key, s)
+			}
+			o.DPI = append(o.DPI, dpi)
+		}
+	}
+	return nil
+}
+
+// parseFileSize - parses '500000', '500k', '5m' as bytes (k = 1024)
+func parseFileSize(s string) (int64, error) {
+	mult := int64(1)
+	switch {
+	case strings.HasSuffix(s, "k"):
+		mult = 1 << 10
+	case strings.HasSuffix(s, "m"):
+		mult = 1 << 20
+	}
+	n, err := strconv.ParseInt(strings.TrimRight(s, "km"), 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid file size %q", s)
+	}
+	return n * mult, nil
+}
+
+// imageExtensions - extensions of the image format, the preferred one goes first
+func imageExtensions(format string) []string {
+	switch format {
+	case "jpeg":
+		return []string{".jpg", ".jpeg"}
+	case "png":
+		return []string{".png"}
+	}
+	return nil
+}
+
+// checkImageExtension - the extension of the file must match the format of its content
+func checkImageExtension(ext string, info *TImageInfo) *TDiagnostic {
+	list := imageExtensions(info.Format)
+	if len(list) == 0 {
+		return nil
+	}
+	for _, v := range list {
+		if strings.EqualFold(v, ext) {
+			return nil
+		}
+	}
+	return newDiag(DiagExtensionMismatch, "ext", list[0], ext,
+		fmt.Sprintf("extension does not match the %v content (want %v, have %v)", info.Format, list[0], ext))
+}
+
+func checkImage(info *TImageInfo, rules tImageRules) TDiagnostics {
+	ret := TDiagnostics{}
+	if len(rules.Formats) > 0 && !isInList(rules.Formats, info.Format) {
+		want := strings.Join(rules.Formats, "|")
+		ret = append(ret, newDiag(DiagImageFormat, "", want, info.Format,
+			fmt.Sprintf("improper image format (want %v, have %v)", want, info.Format)))
+	}
+	if len(rules.ColorModels) > 0 && !isInList(rules.ColorModels, info.ColorModel) {
+		want := strings.Join(rules.ColorModels, "|")
+		ret = append(ret, newDiag(DiagColorModel, "", want, info.ColorModel,
+			fmt.Sprintf("improper colour model (want %v, have %v)", want, info.ColorModel)))
+	}
+	if d := checkYesNo(DiagProgressive, "progressive jpeg", rules.Progressive, info.Progressive); d != nil {
+		ret = append(ret, d)
+	}
+	if d := checkYesNo(DiagAlphaChannel, "alpha channel", rules.Alpha, info.Alpha); d != nil {
+		ret = append(ret, d)
+	}
+	if rules.MaxFileSize > 0 && info.FileSize > rules.MaxFileSize {
+		want := "<=" + strconv.FormatInt(rules.MaxFileSize, 10)
+		have := strconv.FormatInt(info.FileSize, 10)
+		ret = append(ret, newDiag(DiagFileSize, "", want, have,
+			fmt.Sprintf("file is too large (want %v bytes, have %v)", want, have)))
+	}
+	if len(rules.DPI) > 0 && (info.DPI.W != info.DPI.H || !isInIntList(rules.DPI, info.DPI.W)) {
+		list := []string{}
+		for _, dpi := range rules.DPI {
+			list = append(list, strconv.Itoa(dpi))
+		}
+		want := strings.Join(list, "|")
+		have := "none"
+		switch {
+		case info.DPI.W != info.DPI.H:
+			have = info.DPI.String()
+		case info.DPI.W > 0:
+			have = strconv.Itoa(info.DPI.W)
+		}
+		ret = append(ret, newDiag(DiagDPI, "", want, have,
+			fmt.Sprintf("improper dpi (want %v, have %v)", want, have)))
+	}
+	if len(ret) == 0 {
+		return nil
+	}
+	return ret
+}
+
+func checkYesNo(code, what, want string, have bool) *TDiagnostic {
+	if want == "" || (want == "yes") == have {
+		return nil
+	}
+	if have {
+		return newDiag(code, "", "no", "yes", "unexpected "+what)
+	}
+	return newDiag(code, "", "yes", "no", "missing "+what)
+}
+
+func isInIntList(list []int, v int) bool {
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
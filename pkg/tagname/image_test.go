@@ -0,0 +1,237 @@
+package tagname
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func testJpeg(img image.Image, progressive bool, dpi int) []byte {
+	buf := &bytes.Buffer{}
+	jpeg.Encode(buf, img, nil)
+	data := buf.Bytes()
+	if progressive {
+		i := bytes.Index(data, []byte{0xff, 0xc0})
+		data[i+1] = 0xc2
+	}
+	if dpi > 0 {
+		app0 := []byte{0xff, 0xe0, 0, 16, 'J', 'F', 'I', 'F', 0, 1, 1, 1, 0, 0, 0, 0, 0, 0}
+		binary.BigEndian.PutUint16(app0[12:], uint16(dpi))
+		binary.BigEndian.PutUint16(app0[14:], uint16(dpi))
+		data = append(append(append([]byte{}, data[:2]...), app0...), data[2:]...)
+	}
+	return data
+}
+
+// testExifJpeg - a jpeg with an EXIF density (unit 2 - inches, 3 - centimetres)
+func testExifJpeg(img image.Image, order binary.ByteOrder, res, unit int) []byte {
+	tiff := make([]byte, 8+2+3*12+4+8)
+	if order == binary.LittleEndian {
+		copy(tiff, "II")
+	} else {
+		copy(tiff, "MM")
+	}
+	order.PutUint16(tiff[2:], 42)
+	order.PutUint32(tiff[4:], 8)
+	order.PutUint16(tiff[8:], 3)
+	rational := 8 + 2 + 3*12 + 4
+	for i, tag := range []uint16{0x011a, 0x011b, 0x0128} {
+		entry := tiff[10+i*12:]
+		order.PutUint16(entry, tag)
+		order.PutUint32(entry[4:], 1)
+		if tag == 0x0128 {
+			order.PutUint16(entry[2:], 3) // SHORT
+			order.PutUint16(entry[8:], uint16(unit))
+			continue
+		}
+		order.PutUint16(entry[2:], 5) // RATIONAL, both resolutions share the value
+		order.PutUint32(entry[8:], uint32(rational))
+	}
+	order.PutUint32(tiff[rational:], uint32(res))
+	order.PutUint32(tiff[rational+4:], 1)
+	seg := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := append([]byte{0xff, 0xe1, byte((len(seg) + 2) >> 8), byte(len(seg) + 2)}, seg...)
+	data := testJpeg(img, false, 0)
+	return append(append(append([]byte{}, data[:2]...), app1...), data[2:]...)
+}
+
+// testCmykJpeg - a header of a 4 component jpeg, enough to decode its config
+func testCmykJpeg(w, h int) []byte {
+	sof := []byte{0xff, 0xc0, 0, 20, 8, byte(h >> 8), byte(h), byte(w >> 8), byte(w), 4}
+	for id := byte(1); id <= 4; id++ {
+		sof = append(sof, id, 0x11, 0)
+	}
+	sos := []byte{0xff, 0xda, 0, 2}
+	return append(append([]byte{0xff, 0xd8}, sof...), sos...)
+}
+
+func testPng(img image.Image, dpi int) []byte {
+	buf := &bytes.Buffer{}
+	png.Encode(buf, img)
+	data := buf.Bytes()
+	if dpi > 0 {
+		chunk := make([]byte, 4+4+9+4)
+		binary.BigEndian.PutUint32(chunk, 9)
+		copy(chunk[4:], "pHYs")
+		ppm := uint32(float64(dpi)/0.0254 + 0.5)
+		binary.BigEndian.PutUint32(chunk[8:], ppm)
+		binary.BigEndian.PutUint32(chunk[12:], ppm)
+		chunk[16] = 1
+		binary.BigEndian.PutUint32(chunk[17:], crc32.ChecksumIEEE(chunk[4:17]))
+		i := bytes.Index(data, []byte("IDAT")) - 4
+		data = append(append(append([]byte{}, data[:i]...), chunk...), data[i:]...)
+	}
+	return data
+}
+
+func testImage(w, h int, alpha bool) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	if alpha {
+		img.Set(0, 0, color.NRGBA{1, 2, 3, 4})
+	}
+	return img
+}
+
+// testNoise - an image that does not compress well
+func testNoise(w, h int) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	x := uint32(1)
+	for i := range img.Pix {
+		x = x*1664525 + 1013904223
+		img.Pix[i] = byte(x >> 24)
+		if i%4 == 3 {
+			img.Pix[i] = 0xff
+		}
+	}
+	return img
+}
+
+func TestInspectImage(t *testing.T) {
+	gray := image.NewGray(image.Rect(0, 0, 4, 3))
+	paletted := image.NewPaletted(image.Rect(0, 0, 4, 3), color.Palette{color.Black, color.Transparent})
+	table := []struct {
+		name string
+		data []byte
+		info TImageInfo
+	}{
+		{"jpeg", testJpeg(testImage(4, 3, false), false, 0),
+			TImageInfo{Format: "jpeg", Width: 4, Height: 3, ColorModel: "rgb"}},
+		{"progressive jpeg", testJpeg(testImage(4, 3, false), true, 72),
+			TImageInfo{Format: "jpeg", Width: 4, Height: 3, ColorModel: "rgb", Progressive: true, DPI: TResolution{72, 72}}},
+		{"exif jpeg", testExifJpeg(testImage(4, 3, false), binary.BigEndian, 300, 2),
+			TImageInfo{Format: "jpeg", Width: 4, Height: 3, ColorModel: "rgb", DPI: TResolution{300, 300}}},
+		{"exif jpeg in cm", testExifJpeg(testImage(4, 3, false), binary.LittleEndian, 118, 3),
+			TImageInfo{Format: "jpeg", Width: 4, Height: 3, ColorModel: "rgb", DPI: TResolution{300, 300}}},
+		{"gray jpeg", testJpeg(gray, false, 0),
+			TImageInfo{Format: "jpeg", Width: 4, Height: 3, ColorModel: "gray"}},
+		{"cmyk jpeg", testCmykJpeg(4, 3),
+			TImageInfo{Format: "jpeg", Width: 4, Height: 3, ColorModel: "cmyk"}},
+		{"png", testPng(testImage(4, 3, false), 300),
+			TImageInfo{Format: "png", Width: 4, Height: 3, ColorModel: "rgb", DPI: TResolution{300, 300}}},
+		{"alpha png", testPng(testImage(4, 3, true), 0),
+			TImageInfo{Format: "png", Width: 4, Height: 3, ColorModel: "rgb", Alpha: true}},
+		{"gray png", testPng(gray, 0),
+			TImageInfo{Format: "png", Width: 4, Height: 3, ColorModel: "gray"}},
+		{"paletted png", testPng(paletted, 0),
+			TImageInfo{Format: "png", Width: 4, Height: 3, ColorModel: "indexed", Alpha: true}},
+	}
+	for _, v := range table {
+		info, err := inspectImage(v.data)
+		if err != nil {
+			t.Errorf("%v: inspectImage() error: %v", v.name, err)
+			continue
+		}
+		v.info.FileSize = int64(len(v.data))
+		if *info != v.info {
+			t.Errorf("%v:\nhave %+v\nwant %+v", v.name, *info, v.info)
+		}
+	}
+	if _, err := inspectImage([]byte("GIF89a")); err == nil {
+		t.Errorf("inspectImage() of an unknown format: error expected")
+	}
+}
+
+func TestPosterImageCheck(t *testing.T) {
+	defer SetCheckProfile("")
+	profiles, err := ParseCheckProfiles(`
+[images]
+poster.formats     = jpeg
+poster.colormodels = rgb
+poster.progressive = no
+logo.formats       = png
+logo.alpha         = yes
+
+[images.web]
+extends        = images
+poster.maxsize = 8k
+poster.dpi     = 72
+logo.dpi       = 300
+`)
+	if err != nil {
+		t.Fatalf("ParseCheckProfiles() error: %v", err)
+	}
+	for _, profile := range profiles {
+		RegisterCheckProfile(profile)
+	}
+
+	big := testImage(190, 230, false)
+	table := []struct {
+		profile string
+		name    string
+		data    []byte
+		codes   []string
+	}{
+		{"", "the_name_2018__hd_190x230.poster.jpg", testJpeg(big, false, 0), nil},
+		// there are no built-in image limits
+		{"", "the_name_2018__hd_190x230.poster.jpg", testJpeg(big, true, 0), nil},
+		{"", "the_name_2018__hd_logo.poster.png", testPng(testImage(1000, 10, false), 0), nil},
+		// the extension lies, the content is checked
+		{"", "the_name_2018__hd_190x230.poster.jpg", testPng(big, 0), []string{DiagExtensionMismatch}},
+		{"images", "the_name_2018__hd_190x230.poster.jpg", testPng(big, 0), []string{DiagExtensionMismatch, DiagImageFormat}},
+		{"images", "the_name_2018__hd_190x230.poster.jpeg", testJpeg(big, false, 0), nil},
+		{"images", "the_name_2018__hd_190x230.poster.jpg", testJpeg(big, true, 0), []string{DiagProgressive}},
+		{"images", "the_name_2018__hd_190x230.poster.jpg", testJpeg(image.NewGray(big.Bounds()), false, 0), []string{DiagColorModel}},
+		{"images", "the_name_2018__hd_100x230.poster.jpg", testJpeg(big, false, 0), []string{DiagSizeMismatch}},
+		{"images", "the_name_2018__hd_logo.poster.png", testPng(testImage(1000, 10, true), 0), nil},
+		{"images", "the_name_2018__hd_logo.poster.png", testPng(testImage(1000, 10, false), 0), []string{DiagAlphaChannel}},
+		{"images", "the_name_2018__hd_logo.poster.png", testJpeg(testImage(1000, 10, false), false, 0),
+			[]string{DiagExtensionMismatch, DiagImageFormat, DiagAlphaChannel}},
+		{"images.web", "the_name_2018__hd_190x230.poster.jpg", testJpeg(testNoise(190, 230), false, 72), []string{DiagFileSize}},
+		{"images.web", "the_name_2018__hd_190x230.poster.jpg", testJpeg(image.NewRGBA(image.Rect(0, 0, 190, 230)), false, 96),
+			[]string{DiagDPI}},
+		{"images.web", "the_name_2018__hd_logo.poster.png", testPng(testImage(1000, 10, true), 300), nil},
+	}
+	dir := t.TempDir()
+	for i, v := range table {
+		if err := SetCheckProfile(v.profile); err != nil {
+			t.Fatalf("SetCheckProfile() error: %v", err)
+		}
+		path := filepath.Join(dir, v.name)
+		if err := os.WriteFile(path, v.data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		tn, err := NewFromString(dir, v.name, false)
+		if err != nil {
+			t.Errorf("#%v %q: NewFromString() error: %v", i, v.name, err)
+			continue
+		}
+		codes := []string(nil)
+		for _, d := range checkDeep(tn) {
+			codes = append(codes, d.Code)
+		}
+		if !reflect.DeepEqual(codes, v.codes) {
+			t.Errorf("#%v %q: codes %v, want %v (%v)", i, v.name, codes, v.codes, checkDeep(tn))
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package tagname
+
+import (
+	"encoding/json"
+)
+
+// MarshalJSON - tags by type, e.g. {"name":["film"],"year":["2019"]}
+func (o *TTags) MarshalJSON() ([]byte, error) {
+	if o == nil || o.byType == nil {
+		return []byte("null"), nil
+	}
+	return json.Marshal(o.byType)
+}
+
+type tAudioJSON struct {
+	Language string `json:"language"`
+	Channels int    `json:"channels"`
+}
+
+type tFormatJSON struct {
+	Resolution string       `json:"resolution"`
+	Sar        string       `json:"sar,omitempty"`
+	Audio      []tAudioJSON `json:"audio"`
+	Subtitle   []string     `json:"subtitle"`
+	Quality    int          `json:"quality"`
+	CacheType  int          `json:"cache_type"`
+	Sbs        bool         `json:"sbs"`
+
+	FrameRates     []string `json:"frame_rates,omitempty"`
+	FieldOrders    []string `json:"field_orders,omitempty"`
+	PixFmts        []string `json:"pix_fmts,omitempty"`
+	BitDepth       int      `json:"bit_depth,omitempty"`
+	ColorPrimaries []string `json:"color_primaries,omitempty"`
+	ColorTransfers []string `json:"color_transfers,omitempty"`
+	Hdr            bool     `json:"hdr,omitempty"`
+}
+
+// MarshalJSON -
+func (o *TFormat) MarshalJSON() ([]byte, error) {
+	if o == nil {
+		return []byte("null"), nil
+	}
+	ret := tFormatJSON{
+		Resolution: o.resolution.String(),
+		Sar:        o.Sar,
+		Audio:      []tAudioJSON{},
+		Subtitle:   o.Subtitle,
+		Quality:    o.Quality,
+		CacheType:  o.CacheType,
+		Sbs:        o.Sbs,
+
+		FrameRates:     o.FrameRates,
+		FieldOrders:    o.FieldOrders,
+		PixFmts:        o.PixFmts,
+		BitDepth:       o.BitDepth,
+		ColorPrimaries: o.ColorPrimaries,
+		ColorTransfers: o.ColorTransfers,
+		Hdr:            o.Hdr,
+	}
+	for _, a := range o.Audio {
+		ret.Audio = append(ret.Audio, tAudioJSON{a.Language, a.Channels})
+	}
+	if ret.Subtitle == nil {
+		ret.Subtitle = []string{}
+	}
+	return json.Marshal(&ret)
+}
+
+type tCheckJSON struct {
+	Deep        bool         `json:"deep"`
+	OK          bool         `json:"ok"`
+	Diagnostics TDiagnostics `json:"diagnostics"`
+}
+
+type tTagnameJSON struct {
+	Source      string      `json:"source"`
+	Schema      string      `json:"schema"`
+	Tags        *TTags      `json:"tags"`
+	Format      *TFormat    `json:"format"`
+	FormatError string      `json:"format_error,omitempty"`
+	Check       *tCheckJSON `json:"check"`
+}
+
+// MarshalJSON - source, schema, tags by type, the Describe() format and the result of the last check
+func (o *TTagname) MarshalJSON() ([]byte, error) {
+	if o == nil {
+		return []byte("null"), nil
+	}
+	ret := tTagnameJSON{
+		Source: o.Source(),
+		Schema: o.schemaName,
+		Tags:   o.tags,
+	}
+	format, err := o.Describe()
+	if err != nil {
+		ret.FormatError = err.Error()
+	}
+	ret.Format = format
+	if o.lastCheck != nil {
+		diags := o.lastCheck.diags
+		if diags == nil {
+			diags = TDiagnostics{}
+		}
+		ret.Check = &tCheckJSON{
+			Deep:        o.lastCheck.isDeep,
+			OK:          diags.Err() == nil,
+			Diagnostics: diags,
+		}
+	}
+	return json.Marshal(&ret)
+}
@@ -0,0 +1,59 @@
+package tagname
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMarshalJSON(t *testing.T) {
+	tn, err := NewFromString("dir/", "sd_2018_sobibor__12_q0w2_ar2_trailer.mpg", false)
+	if err != nil {
+		t.Fatalf("NewFromString() error: %v", err)
+	}
+	data, err := json.Marshal(tn)
+	if err != nil {
+		t.Fatalf("Marshal() error: %v", err)
+	}
+	want := `{"source":"dir/sd_2018_sobibor__12_q0w2_ar2_trailer.mpg","schema":"rt",` +
+		`"tags":{"agetag":["12"],"atag":["ar2"],"ext":[".mpg"],"name":["sobibor"],"qtag":["q0w2"],"sdhd":["sd"],"type":["trailer"],"year":["2018"]},` +
+		`"format":{"resolution":"720x576","sar":"64:45","audio":[{"language":"rus","channels":2}],"subtitle":[],"quality":0,"cache_type":2,"sbs":false,` +
+		`"frame_rates":["25/1"],"field_orders":["tt","bb","tb","bt"],"pix_fmts":["yuv420p"],"bit_depth":8},` +
+		`"check":{"deep":false,"ok":true,"diagnostics":[]}}`
+	if string(data) != want {
+		t.Errorf("\nhave: %v\nwant: %v", string(data), want)
+	}
+
+	tn, err = NewFromString("", "sobibor_2018__x.mpg", false)
+	if err == nil {
+		t.Fatalf("NewFromString() has no error")
+	}
+	data, err = json.Marshal(tn)
+	if err != nil {
+		t.Fatalf("Marshal() error: %v", err)
+	}
+	v := struct {
+		Format      *struct{} `json:"format"`
+		FormatError string    `json:"format_error"`
+		Check       struct {
+			OK          bool `json:"ok"`
+			Diagnostics []struct {
+				Code     string `json:"code"`
+				Severity string `json:"severity"`
+				Span     TSpan  `json:"span"`
+			} `json:"diagnostics"`
+		} `json:"check"`
+	}{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		t.Fatalf("Unmarshal() error: %v", err)
+	}
+	if v.Format != nil || v.FormatError == "" {
+		t.Errorf("want a format error, have %s", data)
+	}
+	if v.Check.OK || len(v.Check.Diagnostics) != 2 {
+		t.Fatalf("want 2 diagnostics, have %s", data)
+	}
+	d := v.Check.Diagnostics[1]
+	if d.Code != DiagInvalidTagType || d.Severity != "error" || d.Span != (TSpan{14, 15}) {
+		t.Errorf("unexpected diagnostic %+v", d)
+	}
+}
@@ -0,0 +1,128 @@
+package tagname
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+)
+
+// TMigration - a result of the conversion of a tagname to another schema and back
+type TMigration struct {
+	Source     string
+	FromSchema string
+	ToSchema   string
+	Target     string // the converted path
+	Back       string // the target converted back to the source schema (a file name only)
+	// Changed - tags that do not survive the re-parse of the target
+	// ('-typ:val' is lost, '+typ:val' is added)
+	Changed []string
+	// Lost - tags that do not survive the conversion back to the source schema
+	Lost []string
+	Err  error
+}
+
+// IsRoundTrip - the target parses under the target schema to the same tags
+func (o *TMigration) IsRoundTrip() bool {
+	return o.Err == nil && len(o.Changed) == 0
+}
+
+// IsLossless - the target can be converted back without losses
+func (o *TMigration) IsLossless() bool {
+	return o.IsRoundTrip() && len(o.Lost) == 0
+}
+
+// Migrate - converts the tagname to the schema and verifies that the result parses to the
+// same tags and that the conversion back to the schema of the tagname is lossless.
+// A hashtag is generated by a schema, so it is reported as lost only if the conversion
+// back does not generate the same one.
+func Migrate(tn *TTagname, schema string) *TMigration {
+	ret := &TMigration{Changed: []string{}, Lost: []string{}, ToSchema: schema}
+	if err := tn.State(); err != nil {
+		ret.Err = err
+		return ret
+	}
+	ret.Source = tn.Source()
+	ret.FromSchema = tn.Schema()
+
+	target, err := tn.ConvertTo(schema)
+	if err != nil {
+		ret.Err = fmt.Errorf("cannot convert to '%v': %v", schema, err)
+		return ret
+	}
+	ret.Target = target
+
+	_, tags, err := parseTranslated(filepath.Base(target), schema)
+	if err != nil {
+		ret.Err = fmt.Errorf("'%v' does not parse under '%v': %v", filepath.Base(target), schema, err)
+		return ret
+	}
+	ret.Changed = diffTags(tn.tags, tags)
+
+	back, err := ToString(tags, ret.FromSchema)
+	if err != nil {
+		ret.Lost = append(ret.Lost, fmt.Sprintf("cannot convert back to '%v': %v", ret.FromSchema, err))
+		return ret
+	}
+	ret.Back = back
+	backSrcTags, backTags, err := parseTranslated(back, ret.FromSchema)
+	if err != nil {
+		ret.Lost = append(ret.Lost, fmt.Sprintf("'%v' does not parse under '%v': %v", back, ret.FromSchema, err))
+		return ret
+	}
+	ret.Lost = diffTags(tn.tags, backTags)
+	// a hashtag is not a translated tag, a generated one is lost if it is not generated again
+	if hash, err := tn.srcTags.GetTag("hashtag"); err == nil {
+		if backHash, _ := backSrcTags.GetTag("hashtag"); backHash != hash {
+			ret.Lost = append(ret.Lost, "-hashtag:"+hash)
+		}
+	}
+	return ret
+}
+
+// parseTranslated - parses the string under the schema and returns the raw and the translated tags
+func parseTranslated(s string, schemaName string) (*TTags, *TTags, error) {
+	schema, err := Schema(schemaName)
+	if err != nil {
+		return nil, nil, err
+	}
+	srcTags, err := Parse(s, schemaName)
+	if err != nil {
+		return nil, nil, err
+	}
+	tags, err := TranslateTags(srcTags, schema.UnmarshallFilter)
+	if err != nil {
+		return nil, nil, err
+	}
+	return srcTags, tags, nil
+}
+
+// diffTags - returns '-typ:val' for the tags of a that are absent in b and '+typ:val' for the rest of b
+func diffTags(a, b *TTags) []string {
+	count := map[string]int{}
+	for typ, list := range a.byType {
+		for _, val := range list {
+			count[typ+":"+val]++
+		}
+	}
+	for typ, list := range b.byType {
+		for _, val := range list {
+			count[typ+":"+val]--
+		}
+	}
+	ret := []string{}
+	for key, n := range count {
+		for ; n > 0; n-- {
+			ret = append(ret, "-"+key)
+		}
+		for ; n < 0; n++ {
+			ret = append(ret, "+"+key)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i][1:] != ret[j][1:] {
+			return ret[i][1:] < ret[j][1:]
+		}
+		return ret[i] < ret[j]
+	})
+	return ret
+}
@@ -0,0 +1,69 @@
+package tagname
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMigrate(t *testing.T) {
+	type tCase struct {
+		input, schema string
+		target, back  string
+		lost          []string
+		err           string
+	}
+	cases := []tCase{
+		{"sobibor_2018__sd_12_q0w2_ar2.trailer.mpg", "rt",
+			"sd_2018_sobibor__12_q0w2_ar2_trailer.mpg", "sobibor_2018__sd_12_q0w2_ar2.trailer.mpg", []string{}, ""},
+		{"b_2000__3d", "rt",
+			"hd_2000_3d_b__ar6_x2X7vTFuOHU_film", "b_2000__3d_ar6", []string{}, ""},
+		{"b_s01_01_2000__hd_x1234567890", "rt",
+			"hd_2000_b_s01_01__ar6_xSw0GP0CpBF_film", "b_s01_01_2000__hd_ar6", []string{"-hashtag:x1234567890"}, ""},
+		{"hd_2019_the_movie__ar6e2_x1GF40gNCfD_film.mp4", "old",
+			"the_movie_2019__hd_ar6e2.mp4", "hd_2019_the_movie__ar6e2_x1GF40gNCfD_film.mp4", []string{}, ""},
+		{"xxx_s01_01_2000__300x400.jpg", "rt",
+			"", "", []string{}, "must have tag of \"sdhd\" type"},
+	}
+	for i, c := range cases {
+		tn, err := NewFromString("", c.input, false)
+		if tn == nil {
+			t.Fatalf("#%v: cannot parse %v: %v", i, c.input, err)
+		}
+		m := Migrate(tn, c.schema)
+		if c.err != "" {
+			if m.Err == nil || !strings.Contains(m.Err.Error(), c.err) {
+				t.Errorf("#%v: error %v, want %q", i, m.Err, c.err)
+			}
+			continue
+		}
+		if m.Err != nil || !m.IsRoundTrip() {
+			t.Errorf("#%v: unexpected error %v or changes %v", i, m.Err, m.Changed)
+			continue
+		}
+		if m.Target != c.target || m.Back != c.back {
+			t.Errorf("#%v: target %q, back %q, want %q, %q", i, m.Target, m.Back, c.target, c.back)
+		}
+		if !reflect.DeepEqual(m.Lost, c.lost) {
+			t.Errorf("#%v: lost %v, want %v", i, m.Lost, c.lost)
+		}
+		if m.IsLossless() != (len(c.lost) == 0) {
+			t.Errorf("#%v: IsLossless() = %v", i, m.IsLossless())
+		}
+	}
+}
+
+func TestDiffTags(t *testing.T) {
+	a := &TTags{}
+	a.AddTag("name", "x")
+	a.AddTag("atag", "ar2")
+	a.AddTag("qtag", "q0w0")
+	b := &TTags{}
+	b.AddTag("name", "x")
+	b.AddTag("atag", "ar6")
+	b.AddTag("year", "2000")
+	want := []string{"-atag:ar2", "+atag:ar6", "-qtag:q0w0", "+year:2000"}
+	if got := diffTags(a, b); !reflect.DeepEqual(got, want) {
+		t.Errorf("diffTags() = %v, want %v", got, want)
+	}
+}
@@ -6,7 +6,7 @@ var oldForm = `
 entry    = @name [,snen] [,@comment] [,@prttag] ,@year [DIV taglist] ['.' @type] @ext$;
 
 sdhd     = ('sd'|'hd'|'3d'|'4k') !symbol;
-type     = 'trailer'| 'poster' | 'teaser';
+type     = 'trailer'| 'poster' | 'teaser' | 'extra' | 'audio' | 'subtitle';
 ` +
 	// 999x999.poster
 	// logo.poster
@@ -141,8 +141,8 @@ func fnToOldFilter(in, out *TTags, typ, val string, firstRun bool) error {
 			val = size
 		case "film":
 			val = ""
-		case "trailer":
-			val = ".trailer"
+		case "trailer", "teaser", "extra", "audio", "subtitle":
+			val = "." + val
 		}
 	}
 
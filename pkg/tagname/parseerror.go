@@ -0,0 +1,110 @@
+package tagname
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/macroblock/imed/pkg/ptool"
+)
+
+// grammarHelperRules - rules that are not worth to be reported as expected ones
+var grammarHelperRules = map[string]bool{
+	",": true, "entry": true, "taglist": true, "tags": true, "snen": true, "episode": true,
+	"digit": true, "letter": true, "symbol": true, "ident": true, "hex": true, "staglang": true, "poster": true,
+}
+
+// TSchemaError - a failure of a filename under a schema at the farthest position the parser has reached
+type TSchemaError struct {
+	Schema     string
+	Source     string
+	Offset     int      // a byte offset of the offending character, len(Source) at the end
+	Expected   []string // tag rules that have failed at the offset
+	Unexpected bool     // the character is explicitly forbidden at the offset
+	Err        error
+}
+
+func newSchemaError(schema, src string, err error) error {
+	var pe *ptool.TParseError
+	if !errors.As(err, &pe) {
+		return err
+	}
+	ret := &TSchemaError{Schema: schema, Source: src, Offset: pe.Pos.Offset(), Unexpected: pe.Unexpected, Err: err}
+	if ret.Offset > len(src) {
+		ret.Offset = len(src)
+	}
+	for _, name := range pe.Expected {
+		if !grammarHelperRules[name] && !strings.HasPrefix(name, "_") && strings.ToLower(name) == name {
+			ret.Expected = append(ret.Expected, name)
+		}
+	}
+	return ret
+}
+
+// Char - the offending character, an empty string at the end of the source
+func (o *TSchemaError) Char() string {
+	if o.Offset >= len(o.Source) {
+		return ""
+	}
+	_, w := utf8.DecodeRuneInString(o.Source[o.Offset:])
+	return o.Source[o.Offset : o.Offset+w]
+}
+
+// Matched - the part of the source before the offending character
+func (o *TSchemaError) Matched() string {
+	return o.Source[:o.Offset]
+}
+
+// Error -
+func (o *TSchemaError) Error() string {
+	have := "end of name"
+	if c := o.Char(); c != "" {
+		have = fmt.Sprintf("%q", c)
+	}
+	s := fmt.Sprintf("at %v: unexpected %v", utf8.RuneCountInString(o.Matched()), have)
+	if len(o.Expected) > 0 {
+		s += ", expected " + strings.Join(o.Expected, "|")
+	}
+	return s
+}
+
+// Excerpt - the source and a caret under the offending character, every line is prefixed with the indent
+func (o *TSchemaError) Excerpt(indent string) string {
+	caret := strings.Repeat(" ", utf8.RuneCountInString(o.Matched())) + "^"
+	return indent + o.Source + "\n" + indent + caret
+}
+
+// TParseErrors - failures of a filename under every tried schema
+type TParseErrors []error
+
+// Error -
+func (o TParseErrors) Error() string {
+	b := &strings.Builder{}
+	b.WriteString("does not parse under any schema:")
+	for _, err := range o {
+		var se *TSchemaError
+		if !errors.As(err, &se) {
+			fmt.Fprintf(b, "\n  %v", err)
+			continue
+		}
+		fmt.Fprintf(b, "\n  %-6q %v\n%v", se.Schema, se, se.Excerpt("    "))
+	}
+	return b.String()
+}
+
+// SchemaErrors - parse failures of every schema from the error returned by NewFromString
+func SchemaErrors(err error) []*TSchemaError {
+	var list TParseErrors
+	if !errors.As(err, &list) {
+		return nil
+	}
+	ret := []*TSchemaError{}
+	for _, e := range list {
+		var se *TSchemaError
+		if errors.As(e, &se) {
+			ret = append(ret, se)
+		}
+	}
+	return ret
+}
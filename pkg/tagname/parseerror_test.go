@@ -0,0 +1,44 @@
+package tagname
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestSchemaErrors -
+func TestSchemaErrors(t *testing.T) {
+	table := []struct {
+		input    string
+		schema   string
+		offset   int
+		char     string
+		expected string
+		excerpt  string
+	}{
+		{"the_name_2o18__hd.mp4", "old", 14, "_", "year", "the_name_2o18__hd.mp4\n              ^"},
+		{"the_name_2o18__hd.mp4", "rt", 0, "t", "sdhd", "the_name_2o18__hd.mp4\n^"},
+		{"имя_2018__hd.mp4", "rt", 0, "и", "sdhd", "имя_2018__hd.mp4\n^"},
+		{"sd_2018_name__ar6_x!_film.mp4", "rt", 19, "!", "", "sd_2018_name__ar6_x!_film.mp4\n                   ^"},
+	}
+	for _, v := range table {
+		_, err := NewFromString("", v.input, false, v.schema)
+		list := SchemaErrors(err)
+		if len(list) != 1 {
+			t.Errorf("\n%q\nwant a single schema error, have %v", v.input, err)
+			continue
+		}
+		se := list[0]
+		if se.Schema != v.schema || se.Offset != v.offset || se.Char() != v.char {
+			t.Errorf("\n%q\nhave %q at %v %q, want %q at %v %q", v.input, se.Schema, se.Offset, se.Char(), v.schema, v.offset, v.char)
+		}
+		if !strings.Contains(strings.Join(se.Expected, " "), v.expected) {
+			t.Errorf("\n%q\nexpected %v, want %q among them", v.input, se.Expected, v.expected)
+		}
+		if se.Excerpt("") != v.excerpt {
+			t.Errorf("\n%q\nexcerpt\n%v\nwant\n%v", v.input, se.Excerpt(""), v.excerpt)
+		}
+		if d := Diagnostics(err); len(d) != 1 || d[0].Code != DiagParseError || d[0].Span.Start != v.offset {
+			t.Errorf("\n%q\ndiagnostics %+v", v.input, d)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package tagname
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Check profile file format:
+//
+//	// comment
+//	[okko]                        // a profile name
+//	extends        = base         // compose a registered profile first (optional)
+//	must           = agetag       // must-have types of every content type
+//	film.must      = agetag qtag  // ... of films, trailers and teasers only
+//	poster.invalid = agetag       // invalid types of posters and logos
+//	gp.nonunique   = mtag         // non-unique types of gp posters
+//	logo.maxsize   = 500k         // image limits of logos
+//	4k.hdr         = yes          // video limits of 4k films and extras
+//
+// Keys are 'must', 'nonunique', 'invalid', 'invalidvalue' and 'valid'. A key
+// without a 'film.', 'poster.', 'logo.', 'gp.', 'extra.', 'audio.' or 'subtitle.'
+// prefix applies to every content type.
+// Image limits of the deep check ('formats', 'colormodels', 'progressive',
+// 'alpha', 'maxsize' and 'dpi') override the extended ones instead of adding to
+// them. There are no built-in image limits, platform policies are profiles.
+// Video limits are set by format, e.g. 'film.4k.pixfmts' or '4k.hdr' for every
+// type ('framerates', 'fieldorders', 'pixfmts', 'bitdepth', 'primaries',
+// 'transfers' and 'hdr'). They override the built-in and the extended ones.
+// The 'poster.' rules apply to logos as well, the 'logo.' ones override them.
+// A file may contain several profiles. Rules of a profile are added to the
+// built-in ones the same way updateCheckContext composes the contexts of types.
+
+// TCheckProfile - a named set of additional check rules
+type TCheckProfile struct {
+	name     string
+	extends  string
+	contexts map[string]*tCheckContext // by scope: "" (every type), "film", "poster", "logo", "gp", "extra", ...
+}
+
+var globCheckProfiles = map[string]*TCheckProfile{}
+
+// activeCheckProfile - the composed contexts of the selected profile, guarded by checkContextMtx
+var activeCheckProfile map[string]*tCheckContext
+
+var checkProfileScopes = map[string]bool{
+	"": true, "film": true, "poster": true, "logo": true, "gp": true, "extra": true, "audio": true, "subtitle": true,
+}
+
+// imageScopes, videoScopes - scopes image and video limits are accepted in, other types have none to check
+var (
+	imageScopes = map[string]bool{"": true, "poster": true, "logo": true, "gp": true}
+	videoScopes = map[string]bool{"": true, "film": true, "extra": true}
+)
+
+func newCheckProfile(name string) *TCheckProfile {
+	ret := &TCheckProfile{name: name, contexts: map[string]*tCheckContext{}}
+	for scope := range checkProfileScopes {
+		ret.contexts[scope] = newCheckContext()
+	}
+	return ret
+}
+
+func newCheckContext() *tCheckContext {
+	return &tCheckContext{
+		ListMustHaveTypes: []string{},
+		TabNonUniqueTypes: map[string]uint8{},
+		TabInvalidTypes:   map[string]uint8{},
+		TabInvalidValues:  map[string]uint8{},
+		TabValidTypes:     map[string]uint8{},
+	}
+}
+
+// Name -
+func (o *TCheckProfile) Name() string {
+	return o.name
+}
+
+func (o *TCheckProfile) set(key, val string) error {
+	scope, field, ok := strings.Cut(key, ".")
+	switch {
+	case !ok:
+		scope, field = "", key
+	case isVideoFormat(scope):
+		// a video limit of every type, e.g. '4k.hdr'
+		scope, field = "", key
+	case scope == "" || !checkProfileScopes[scope]:
+		return fmt.Errorf("unknown scope %q (want 'film', 'poster', 'logo', 'gp', 'extra', 'audio' or 'subtitle')", scope)
+	}
+	cc := o.contexts[scope]
+	var table map[string]uint8
+	switch field {
+	default:
+		if sdhd, f, ok := strings.Cut(field, "."); ok {
+			if !videoScopes[scope] {
+				return fmt.Errorf("unknown key %q", key)
+			}
+			return cc.setVideoRule(sdhd, f, val)
+		}
+		if !imageScopes[scope] {
+			return fmt.Errorf("unknown key %q", key)
+		}
+		return cc.Image.set(field, val)
+	case "must":
+		cc.ListMustHaveTypes = append(cc.ListMustHaveTypes, strings.Fields(val)...)
+		return nil
+	case "nonunique":
+		table = cc.TabNonUniqueTypes
+	case "invalid":
+		table = cc.TabInvalidTypes
+	case "invalidvalue":
+		table = cc.TabInvalidValues
+	case "valid":
+		table = cc.TabValidTypes
+	}
+	for _, s := range strings.Fields(val) {
+		table[s] = 0
+	}
+	return nil
+}
+
+// ParseCheckProfiles - builds profiles from a definition text. It does not register them.
+func ParseCheckProfiles(src string) ([]*TCheckProfile, error) {
+	ret := []*TCheckProfile{}
+	var profile *TCheckProfile
+
+	scanner := bufio.NewScanner(strings.NewReader(src))
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Text()
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			name := strings.TrimSpace(line[1 : len(line)-1])
+			if name == "" {
+				return nil, fmt.Errorf("line %v: empty profile name", lineNo)
+			}
+			profile = newCheckProfile(name)
+			ret = append(ret, profile)
+			continue
+		}
+		if profile == nil {
+			return nil, fmt.Errorf("line %v: want '[profile name]' first, have %q", lineNo, line)
+		}
+		key, val, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %v: want 'key = value', have %q", lineNo, line)
+		}
+		key = strings.TrimSpace(key)
+		val = strings.TrimSpace(val)
+		if key == "extends" {
+			profile.extends = val
+			continue
+		}
+		if err := profile.set(key, val); err != nil {
+			return nil, fmt.Errorf("line %v: %v", lineNo, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// LoadCheckProfiles - reads a check profile file and registers its profiles under their names.
+func LoadCheckProfiles(path string) ([]*TCheckProfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	list, err := ParseCheckProfiles(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", path, err)
+	}
+	for _, profile := range list {
+		RegisterCheckProfile(profile)
+	}
+	return list, nil
+}
+
+// RegisterCheckProfile -
+func RegisterCheckProfile(profile *TCheckProfile) {
+	globCheckProfiles[strings.ToLower(profile.name)] = profile
+}
+
+// CheckProfiles - names of the registered profiles
+func CheckProfiles() []string {
+	keys := make([]string, 0, len(globCheckProfiles))
+	for key := range globCheckProfiles {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// composeCheckProfile - merges the contexts of the profile and of the profiles it extends
+func composeCheckProfile(name string, visited map[string]bool) (map[string]*tCheckContext, error) {
+	name = strings.ToLower(name)
+	profile, ok := globCheckProfiles[name]
+	if !ok {
+		return nil, fmt.Errorf("%q is not a registered check profile", name)
+	}
+	if visited[name] {
+		return nil, fmt.Errorf("check profile %q extends itself", name)
+	}
+	visited[name] = true
+	ret := profile.contexts
+	if profile.extends != "" {
+		base, err := composeCheckProfile(profile.extends, visited)
+		if err != nil {
+			return nil, err
+		}
+		ret = map[string]*tCheckContext{}
+		for scope := range checkProfileScopes {
+			ret[scope] = updateCheckContext(nil, base[scope], profile.contexts[scope])
+		}
+	}
+	return ret, nil
+}
+
+// SetCheckProfile - selects the registered profile the checks use, an empty name selects the built-in rules only
+func SetCheckProfile(name string) error {
+	var contexts map[string]*tCheckContext
+	if name != "" {
+		var err error
+		contexts, err = composeCheckProfile(name, map[string]bool{})
+		if err != nil {
+			return err
+		}
+	}
+	checkContextMtx.Lock()
+	defer checkContextMtx.Unlock()
+	activeCheckProfile = contexts
+	filmsCheckContext = nil
+	postersCheckContext = nil
+	logosCheckContext = nil
+	gpPostersCheckContext = nil
+	extrasCheckContext = nil
+	audioCheckContext = nil
+	subtitlesCheckContext = nil
+	return nil
+}
+
+// composeCheckContext - the built-in context with the rules of the active profile for the scopes,
+// a later scope is the more specific one. Must be called with checkContextMtx locked.
+func composeCheckContext(builtin *tCheckContext, scopes ...string) *tCheckContext {
+	common, typed := defaultCheckContext, builtin
+	if activeCheckProfile != nil {
+		common = updateCheckContext(nil, common, activeCheckProfile[""])
+		// video limits of every type ('4k.hdr') override the built-in ones of the type as well
+		typed = updateCheckContext(nil, typed, &tCheckContext{Video: activeCheckProfile[""].Video})
+		for _, scope := range scopes {
+			typed = updateCheckContext(nil, typed, activeCheckProfile[scope])
+		}
+	}
+	return updateCheckContext(nil, common, typed)
+}
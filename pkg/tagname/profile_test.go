@@ -0,0 +1,103 @@
+package tagname
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+var testCheckProfiles = `
+// agetag is mandatory for one platform and forbidden for another
+[agerated]
+film.must = agetag
+
+[noage]
+invalid = agetag
+
+[strict]
+extends = agerated
+film.must = qtag
+poster.nonunique = mtag
+`
+
+// TestCheckProfile -
+func TestCheckProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.profiles")
+	if err := os.WriteFile(path, []byte(testCheckProfiles), 0644); err != nil {
+		t.Fatal(err)
+	}
+	list, err := LoadCheckProfiles(path)
+	if err != nil {
+		t.Fatalf("LoadCheckProfiles() error: %v", err)
+	}
+	defer func() {
+		for _, p := range list {
+			delete(globCheckProfiles, p.Name())
+		}
+		SetCheckProfile("")
+	}()
+
+	table := []struct {
+		profile, input string
+		codes          []string
+	}{
+		{"", "the_name_2018__hd.trailer.mp4", nil},
+		{"", "the_name_2018__hd_18.trailer.mp4", nil},
+		{"agerated", "the_name_2018__hd.trailer.mp4", []string{DiagMissingTag}},
+		{"agerated", "the_name_2018__hd_18.trailer.mp4", nil},
+		{"agerated", "the_name_2018__hd_600x600.poster.jpg", nil},
+		{"noage", "the_name_2018__hd.trailer.mp4", nil},
+		{"noage", "the_name_2018__hd_18.trailer.mp4", []string{DiagInvalidTagType}},
+		{"strict", "the_name_2018__hd_18.trailer.mp4", []string{DiagMissingTag}},
+		{"strict", "the_name_2018__hd_18_q0w0.trailer.mp4", nil},
+		{"strict", "the_name_2018__hd_q0w0.trailer.mp4", []string{DiagMissingTag}},
+	}
+	for _, v := range table {
+		if err := SetCheckProfile(v.profile); err != nil {
+			t.Fatalf("SetCheckProfile(%q) error: %v", v.profile, err)
+		}
+		_, err := NewFromString("", v.input, false)
+		codes := []string(nil)
+		for _, d := range Diagnostics(err) {
+			codes = append(codes, d.Code)
+		}
+		if !reflect.DeepEqual(codes, v.codes) {
+			t.Errorf("%q %q: codes %v, want %v (%v)", v.profile, v.input, codes, v.codes, err)
+		}
+	}
+
+	if err := SetCheckProfile("unknown"); err == nil {
+		t.Errorf("SetCheckProfile(\"unknown\") has no error")
+	}
+}
+
+// TestParseCheckProfilesIncorrect -
+func TestParseCheckProfilesIncorrect(t *testing.T) {
+	table := []string{
+		"must = agetag",
+		"[]\nmust = agetag",
+		"[x]\nmust",
+		"[x]\nwhatever = 1",
+		"[x]\nseries.must = agetag",
+		"[x]\n.must = agetag",
+		// image limits of types without an image
+		"[x]\nfilm.maxsize = 2m",
+		"[x]\naudio.dpi = 72",
+		"[x]\nextra.formats = png",
+		// video limits of types without a video
+		"[x]\nposter.hd.pixfmts = yuv420p",
+		"[x]\naudio.4k.hdr = yes",
+	}
+	for _, v := range table {
+		if _, err := ParseCheckProfiles(v); err == nil {
+			t.Errorf("\n%q\nhas no error", v)
+		}
+	}
+
+	RegisterCheckProfile(&TCheckProfile{name: "loop", extends: "loop", contexts: newCheckProfile("loop").contexts})
+	defer delete(globCheckProfiles, "loop")
+	if err := SetCheckProfile("loop"); err == nil {
+		t.Errorf("a profile that extends itself has no error")
+	}
+}
@@ -0,0 +1,240 @@
+package tagname
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// tTagGen - builds random valid tag sets in the internal (translated) form
+type tTagGen struct {
+	rnd *rand.Rand
+}
+
+var reservedWords = map[string]bool{
+	"zzz": true, "sd": true, "hd": true, "3d": true, "4k": true, "logo": true, "poster": true,
+	"film": true, "trailer": true, "teaser": true, "sxx": true,
+}
+
+func (o *tTagGen) chance(percent int) bool {
+	return o.rnd.Intn(100) < percent
+}
+
+func (o *tTagGen) pick(list ...string) string {
+	return list[o.rnd.Intn(len(list))]
+}
+
+func (o *tTagGen) word() string {
+	for {
+		b := []byte{byte('a' + o.rnd.Intn(26))}
+		for n := o.rnd.Intn(7); n > 0; n-- {
+			b = append(b, byte('a'+o.rnd.Intn(26)))
+		}
+		if s := string(b); !reservedWords[s] {
+			return s
+		}
+	}
+}
+
+func (o *tTagGen) words(max int) string {
+	list := []string{o.word()}
+	for n := o.rnd.Intn(max); n > 0; n-- {
+		list = append(list, o.word())
+	}
+	return strings.Join(list, "_")
+}
+
+func (o *tTagGen) exx() string {
+	from := 1 + o.rnd.Intn(20)
+	switch o.rnd.Intn(3) {
+	case 0:
+		return fmt.Sprintf("%02d", from)
+	case 1:
+		return fmt.Sprintf("%02d-%02d", from, from+1+o.rnd.Intn(5))
+	}
+	return fmt.Sprintf("%02d-%02d+%02d", from, from+1, from+3+o.rnd.Intn(5))
+}
+
+// mtag - a meta tag that is neither another tag nor replaced by normalization
+func (o *tTagGen) mtag() string {
+	for {
+		s := "m" + o.word()
+		if _, val := filterFixCommonTags("mtag", s); val != s {
+			continue
+		}
+		if tags, err := Parse("a_2000__"+s, "old"); err == nil && tags.GetTags("mtag") != nil {
+			return s
+		}
+	}
+}
+
+func (o *tTagGen) atag() string {
+	s := "a"
+	for n := 1 + o.rnd.Intn(3); n > 0; n-- {
+		s += o.pick("r", "e", "eng", "fra", "chn") + o.pick("1", "2", "6", "8")
+	}
+	return s
+}
+
+func (o *tTagGen) tags() *TTags {
+	tags := &TTags{}
+	tags.AddTag("type", o.pick("film", "trailer"))
+	tags.AddTag("name", o.words(3))
+	if o.chance(30) {
+		tags.AddTag("sxx", fmt.Sprintf("s%02d", 1+o.rnd.Intn(30)))
+		if o.chance(60) {
+			tags.AddTag("exx", o.exx())
+			if o.chance(30) {
+				tags.AddTag("ename", o.words(2))
+			}
+		}
+	}
+	if o.chance(20) {
+		tags.AddTag("comment", "zzz_"+o.words(2))
+	}
+	tags.AddTag("year", fmt.Sprint(1900+o.rnd.Intn(130)))
+	tags.AddTag("sdhd", o.pick("sd", "hd", "4k", "3d"))
+	tags.AddTag("atag", o.atag())
+	if o.chance(30) {
+		tags.AddTag("stag", "s"+o.pick("r", "s", "rs"))
+	}
+	if o.chance(50) {
+		tags.AddTag("agetag", o.pick("00", "06", "12", "16", "18"))
+	}
+	if o.chance(40) {
+		tags.AddTag("qtag", fmt.Sprintf("q%v%v%v", o.rnd.Intn(10), o.pick("w", "s"), o.rnd.Intn(10)))
+	}
+	if o.chance(10) {
+		tags.AddTag("mtag", o.mtag())
+	}
+	if o.chance(10) {
+		// every smoking tag is normalized to 'xsmoking'
+		tags.AddTag("smktag", "xsmoking")
+	}
+	if o.chance(10) {
+		tags.AddTag("vtag", o.pick("vgoblin", "vlostfilm"))
+	}
+	if o.chance(10) {
+		tags.AddTag("prttag", fmt.Sprintf("prt%012d", o.rnd.Int63n(1e12)))
+	}
+	tags.AddTag("ext", o.pick(".mp4", ".mpg", ".mkv", ""))
+	return tags
+}
+
+// TestRoundTrip - ToString -> Parse -> ToString must be stable for random tag sets in every schema
+func TestRoundTrip(t *testing.T) {
+	gen := &tTagGen{rand.New(rand.NewSource(1))}
+	for i := 0; i < 1000; i++ {
+		tags := gen.tags()
+		for _, schema := range Schemas() {
+			s1, err := ToString(tags, schema)
+			if err != nil {
+				t.Errorf("#%v %v: ToString(%v) error: %v", i, schema, tags.byType, err)
+				continue
+			}
+			_, tags2, err := parseTranslated(s1, schema)
+			if err != nil {
+				t.Errorf("#%v %v: %q does not parse: %v", i, schema, s1, err)
+				continue
+			}
+			if diff := diffTags(tags, tags2); len(diff) > 0 {
+				t.Errorf("#%v %v: %q parses to other tags: %v", i, schema, s1, diff)
+			}
+			s2, err := ToString(tags2, schema)
+			if err != nil {
+				t.Errorf("#%v %v: ToString(%q) error: %v", i, schema, s1, err)
+				continue
+			}
+			if s1 != s2 {
+				t.Errorf("#%v %v: unstable %q -> %q", i, schema, s1, s2)
+			}
+		}
+	}
+}
+
+// FuzzNewFromString - parsing and using an arbitrary name must never panic
+func FuzzNewFromString(f *testing.F) {
+	for _, c := range tableOldSchemaParseCorrect {
+		f.Add(c.inputVal)
+	}
+	for _, c := range tableRtSchemaParseCorrect {
+		f.Add(c.inputVal)
+	}
+	for _, s := range tableOldSchemaParseIncorrect {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		tn, _ := NewFromString("", s, false)
+		if tn == nil {
+			return
+		}
+		for _, schema := range Schemas() {
+			tn.ConvertTo(schema)
+		}
+		tn.Describe()
+		tn.GetQuality()
+		tn.GetAudio()
+		tn.GetSubtitle()
+		tn.Episodes()
+		tn.MarshalJSON()
+	})
+}
+
+// FuzzTagValues - the accessors must not panic on values set by a script
+func FuzzTagValues(f *testing.F) {
+	f.Add("q1w0", "ar6e2", "sr")
+	f.Add("q", "a", "s")
+	f.Add("", "", "")
+	f.Add("qxw0", "a2r", "x")
+	f.Fuzz(func(t *testing.T, qtag, atag, stag string) {
+		tn, err := NewFromString("", "sd_2000_a__film", false)
+		if tn == nil {
+			t.Fatal(err)
+		}
+		tn.SetTag("qtag", qtag)
+		tn.SetTag("atag", atag)
+		tn.SetTag("stag", stag)
+		tn.Describe()
+		tn.GetQuality()
+		tn.GetAudio()
+		tn.GetSubtitle()
+	})
+}
+
+func TestMalformedTagValues(t *testing.T) {
+	type tCase struct {
+		typ, val string
+		ok       bool
+	}
+	cases := []tCase{
+		{"qtag", "q1w0", true},
+		{"qtag", "q1x0", false},
+		{"qtag", "qxw0", false},
+		{"qtag", "q1w", false},
+		{"atag", "ar6e2", true},
+		{"atag", "a2r", false},
+		{"atag", "ar", false},
+		{"atag", "x", false},
+		{"stag", "sr", true},
+		{"stag", "s", false},
+	}
+	for i, c := range cases {
+		tn, err := NewFromString("", "sd_2000_a__film", false)
+		if tn == nil {
+			t.Fatal(err)
+		}
+		tn.SetTag(c.typ, c.val)
+		switch c.typ {
+		case "qtag":
+			_, err = tn.GetQuality()
+		case "atag":
+			_, err = tn.GetAudio()
+		case "stag":
+			_, err = tn.GetSubtitle()
+		}
+		if (err == nil) != c.ok {
+			t.Errorf("#%v %v %q: error %v, want ok = %v", i, c.typ, c.val, err, c.ok)
+		}
+	}
+}
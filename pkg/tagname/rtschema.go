@@ -13,7 +13,7 @@ entry       =  (@_hackHD3D @sdhd, @year, @_hack3D,| !(,) @sdhd, @year,) @name [,
 sdhd        = ['sd'|'hd'|'4k'];
 _hackHD3D   = 'hd' !('hd',|'3d',);
 _hack3D     = '3d';
-type        = 'trailer'|'film'|'teaser'| 'logo' | poster;
+type        = 'trailer'|'film'|'teaser'|'extra'|'audio'|'subtitle'| 'logo' | poster;
 
 poster      = ('poster' sizetag) | 'logo';
 
@@ -52,14 +52,24 @@ var reRes = regexp.MustCompile(`\d+x\d+`)
 // // unreachable
 // }
 
+// genHashTag - the key does not include episodes, so every episode, range or list
+// of episodes of the season gets the same hash
 func genHashTag(tags *TTags) string {
+	return "x" + hash.Get(hashKey(tags))
+}
+
+// hashKey - a title key the hashtag is generated from
+func hashKey(tags *TTags) string {
+	sdhd, _ := tags.GetTag("sdhd")
+	return titleKey(tags, sdhd)
+}
+
+func titleKey(tags *TTags, sdhd string) string {
 	name, _ := tags.GetTag("name")
 	sxx, _ := tags.GetTag("sxx")
 	year, _ := tags.GetTag("year")
-	sdhd, _ := tags.GetTag("sdhd")
 	comment, _ := tags.GetTag("comment")
-	key := name + "_" + sxx + "_" + year + "_" + sdhd + "_" + comment
-	return "x" + hash.Get(key)
+	return hash.Key(name, sxx, year, sdhd, comment)
 }
 
 func fnFromRTFilter(in, out *TTags, typ, val string, firstRun bool) error {
@@ -4,14 +4,17 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"sync"
 
 	"github.com/macroblock/imed/pkg/ptool"
 )
 
 // TSchema -
 type TSchema struct {
-	name   string
-	parser **ptool.TParser
+	name    string
+	grammar string
+	parser  **ptool.TParser
+	mtx     sync.Mutex // the parser keeps its state while parsing
 	// MustHaveByType          []string
 	// NonUniqueByType         []string // can be placed multiple times
 	// Valid                   []string
@@ -40,7 +43,8 @@ ZZZ      = 'zzz';
 
 snen     = @sxx [,@sname] [,@exx [,@ename]];
 sxx      = 's' (digit digit | 'xx' | 'XX' | 'xX' | 'Xx');
-exx      = !(EONAME) digit digit [digit] ['a'|'b'];
+exx      = !(EONAME) episode {('-'|'+') episode};
+episode  = digit digit [digit] ['a'|'b'];
 name     = !(EONAME|sxx,|ZZZ,) ident {, !(EONAME|sxx,|ZZZ,) ident};
 sname    = !(EONAME|exx,|ZZZ,) ident {, !(EONAME|exx,|ZZZ,) ident};
 ename    = !(EONAME|     ZZZ,) ident {, !(EONAME|     ZZZ,) ident};
@@ -60,9 +64,9 @@ tags     = @INVALID_TAG | @EXCLUSIVE_TAGS
 qtag      = 'q'digit('w'|'s')digit !symbol;
 atag      = 'a' ( letter letter letter | 'r' | 'e' ) digit {( letter letter letter | 'r' | 'e' ) digit} !symbol;
 stag      = 's' staglang {staglang} !symbol;
-agetag    = ('00'|'06'|'12'|'16'|'18'|'99') !symbol;
+agetag    = (${agetag}) !symbol;
 alreadyagedtag = digit digit 'aged' !symbol;
-vtag      = 'v' ('goblin'|'kurazhbambey'|'lostfilm'|'newstudio'|'pozitiv'|ERR_invalid_vtag) !symbol;
+vtag      = 'v' (${vtag}|ERR_invalid_vtag) !symbol;
 hardsubtag= ('mhardsub'|'hardsub'|'xhardsub') !symbol;
 smktag    = ('xsmoking'|'xsmk'|'msmoking'|'msmk'|'smoking'|'smk') !symbol;
 alcotag   = 'xalcohol' !symbol;
@@ -74,9 +78,7 @@ datetag   = 'd' digit digit digit digit digit digit digit digit digit digit !sym
 prttag    = ('prt'|'PRT') digit digit digit digit digit digit digit digit digit digit digit digit !symbol;
 hashtag   = 'x' symbol symbol symbol symbol symbol symbol symbol symbol symbol symbol !symbol;
 
-EXCLUSIVE_TAGS = ('amed'|'abc'|'pb'|'vp'|'disney'|('dop'{symbol})|'oscar'|'dk'|'ru'|'pryamoiz'
-            |'newstudio'|'pozitiv'|'lostfilm'
-                 ) !symbol;
+EXCLUSIVE_TAGS = (${EXCLUSIVE_TAGS}|('dop'{symbol})) !symbol;
 
 UNKNOWN_TAG = !'poster' symbol{symbol};
 
@@ -132,6 +134,11 @@ func Schema(name string) (*TSchema, error) {
 	return ret, nil
 }
 
+// Name -
+func (o *TSchema) Name() string {
+	return o.name
+}
+
 // Schemas -
 func Schemas() []string {
 	keys := make([]string, 0, len(globSchemas))
@@ -145,11 +152,11 @@ func Schemas() []string {
 func filterFixCommonTags(typ, val string) (string, string) {
 	switch typ {
 	case "EXCLUSIVE_TAGS":
-		switch val {
+		switch {
 		default:
 			typ = "mtag"
 			val = "m" + val
-		case "newstudio", "pozitiv", "lostfilm":
+		case isVocabularyWord("vtag", val): // a dubbing studio
 			typ = "vtag"
 			val = "v" + val
 		}
@@ -197,17 +204,19 @@ func filterFixCommonTags(typ, val string) (string, string) {
 		val = "x" + val
 	case "prttag":
 		val = strings.ToLower(val)
+	case "exx":
+		val = normalizeExx(val)
 	}
 	return typ, val
 }
 
-// fixATag - add ar6 for hd movies or ar2 for sd movies or trailers. Do nothing for other types
+// fixATag - add ar6 for hd movies or ar2 for sd movies, trailers or extras. Do nothing for other types
 func fixATag(tags *TTags) error {
 	typ, err := tags.GetTag("type")
 	if err != nil {
 		return err
 	}
-	if typ != "trailer" && typ != "film" {
+	if typ != "trailer" && typ != "film" && typ != "extra" {
 		return nil
 	}
 
@@ -220,7 +229,7 @@ func fixATag(tags *TTags) error {
 		return err
 	}
 
-	if sdhd == "sd" || typ == "trailer" {
+	if sdhd == "sd" || typ != "film" {
 		tags.AddTag("atag", "ar2")
 		return nil
 	}
@@ -228,13 +237,13 @@ func fixATag(tags *TTags) error {
 	return nil
 }
 
-// unfixATag - remove ar6 for hd movies or ar2 for sd movies or trailers. Do nothing for other types
+// unfixATag - remove ar6 for hd movies or ar2 for sd movies, trailers or extras. Do nothing for other types
 func unfixATag(tags *TTags) error {
 	typ, err := tags.GetTag("type")
 	if err != nil {
 		return err
 	}
-	if typ != "trailer" && typ != "film" {
+	if typ != "trailer" && typ != "film" && typ != "extra" {
 		return nil
 	}
 
@@ -249,7 +258,7 @@ func unfixATag(tags *TTags) error {
 	}
 
 	switch {
-	case atag == "ar2" && (sdhd == "sd" || typ == "trailer"):
+	case atag == "ar2" && (sdhd == "sd" || typ != "film"):
 		tags.RemoveTags("atag")
 	case atag == "ar6" && (sdhd == "hd" || sdhd == "3d") && typ == "film":
 		tags.RemoveTags("atag")
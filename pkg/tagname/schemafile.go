@@ -0,0 +1,206 @@
+package tagname
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/macroblock/imed/pkg/ptool"
+)
+
+// Schema definition file format:
+//
+//	// comment
+//	name    = nf                      // schema name (required)
+//	filters = rt                      // use filters of a registered schema after the rules (optional)
+//	include = body                    // append the common tag rules to the grammar (optional)
+//	head    = sdhd year name _ atag   // ToStringHeadOrderByType
+//	tail    = hashtag type ext        // ToStringTailOrderByType
+//	read    = UNKNOWN_TAG:SD -> sdhd:sd
+//	write   = type:film -> type:
+//	[grammar]
+//	entry   = ...
+//
+// 'read' rules are applied on parse, 'write' rules on ToString. A rule is
+// 'typ:val -> typ:val' where '*' means any value on the left side and the
+// original value on the right side. An empty right side removes the tag.
+
+type tTranslateRule struct {
+	from *Tag
+	to   *Tag
+}
+
+func newTranslateRule(s string) (*tTranslateRule, error) {
+	left, right, ok := strings.Cut(s, "->")
+	if !ok {
+		return nil, fmt.Errorf("invalid rule %q (want 'typ:val -> typ:val')", s)
+	}
+	from, err := NewTag(left)
+	if err != nil {
+		return nil, err
+	}
+	ret := &tTranslateRule{from: from}
+	if strings.TrimSpace(right) == "" {
+		return ret, nil
+	}
+	ret.to, err = NewTag(right)
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+func applyRules(rules []*tTranslateRule, typ, val string) (string, string) {
+	for _, rule := range rules {
+		if !rule.from.Match(typ, val) {
+			continue
+		}
+		if rule.to == nil {
+			return "", ""
+		}
+		return rule.to.Apply(typ, val)
+	}
+	return typ, val
+}
+
+func fnCopyFilter(in, out *TTags, typ, val string, firstRun bool) error {
+	if typ == "" && val == "" {
+		return nil
+	}
+	out.AddTag(typ, val)
+	return nil
+}
+
+func makeRuleFilter(rules []*tTranslateRule, next func(in, out *TTags, typ, val string, firstRun bool) error) func(in, out *TTags, typ, val string, firstRun bool) error {
+	return func(in, out *TTags, typ, val string, firstRun bool) error {
+		if typ == "" && val == "" {
+			return next(in, out, typ, val, firstRun)
+		}
+		typ, val = applyRules(rules, typ, val)
+		if typ == "" {
+			return nil
+		}
+		return next(in, out, typ, val, firstRun)
+	}
+}
+
+// buildParser - builds the parser of the grammar with the vocabulary lists spliced in
+func buildParser(grammar string) (*ptool.TParser, error) {
+	return ptool.NewBuilder().FromString(spliceVocabulary(grammar)).Entries("entry").Build()
+}
+
+// ParseSchemaDefinition - builds a schema from a definition text. It does not register the schema.
+func ParseSchemaDefinition(src string) (*TSchema, error) {
+	var name, filters string
+	var readRules, writeRules []*tTranslateRule
+	var grammar []string
+	ret := &TSchema{}
+	isGrammar := false
+	includeBody := false
+
+	scanner := bufio.NewScanner(strings.NewReader(src))
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Text()
+		if isGrammar {
+			grammar = append(grammar, line)
+			continue
+		}
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if line == "[grammar]" {
+			isGrammar = true
+			continue
+		}
+		key, val, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %v: want 'key = value', have %q", lineNo, line)
+		}
+		key = strings.TrimSpace(key)
+		val = strings.TrimSpace(val)
+		switch key {
+		default:
+			return nil, fmt.Errorf("line %v: unknown key %q", lineNo, key)
+		case "name":
+			name = val
+		case "filters":
+			filters = val
+		case "include":
+			if val != "body" {
+				return nil, fmt.Errorf("line %v: unsupported include %q", lineNo, val)
+			}
+			includeBody = true
+		case "head":
+			ret.ToStringHeadOrderByType = append(ret.ToStringHeadOrderByType, strings.Fields(val)...)
+		case "tail":
+			ret.ToStringTailOrderByType = append(ret.ToStringTailOrderByType, strings.Fields(val)...)
+		case "read", "write":
+			rule, err := newTranslateRule(val)
+			if err != nil {
+				return nil, fmt.Errorf("line %v: %v", lineNo, err)
+			}
+			if key == "read" {
+				readRules = append(readRules, rule)
+			} else {
+				writeRules = append(writeRules, rule)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if name == "" {
+		return nil, fmt.Errorf("schema name is not defined")
+	}
+	if !isGrammar {
+		return nil, fmt.Errorf("schema %q: [grammar] section is not defined", name)
+	}
+
+	text := strings.Join(grammar, "\n")
+	if includeBody {
+		text += "\n" + body
+	}
+	parser, err := buildParser(text)
+	if err != nil {
+		return nil, fmt.Errorf("schema %q: parser error: %v", name, err)
+	}
+	ret.name = name
+	ret.grammar = text
+	ret.parser = &parser
+
+	ret.UnmarshallFilter = fnCopyFilter
+	ret.MarshallFilter = fnCopyFilter
+	if filters != "" {
+		base, err := Schema(filters)
+		if err != nil {
+			return nil, fmt.Errorf("schema %q: %v", name, err)
+		}
+		ret.UnmarshallFilter = base.UnmarshallFilter
+		ret.MarshallFilter = base.MarshallFilter
+	}
+	ret.UnmarshallFilter = makeRuleFilter(readRules, ret.UnmarshallFilter)
+	ret.MarshallFilter = makeRuleFilter(writeRules, ret.MarshallFilter)
+	return ret, nil
+}
+
+// LoadSchemaFile - reads a schema definition file and registers the schema under its name.
+func LoadSchemaFile(path string) (*TSchema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	schema, err := ParseSchemaDefinition(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", path, err)
+	}
+	RegisterSchema(schema.name, schema)
+	return schema, nil
+}
@@ -0,0 +1,91 @@
+package tagname
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var testSchemaDefinition = `
+// test delivery schema
+name    = testpartner
+filters = rt
+include = body
+head    = name sxx sname exx ename comment year _ sdhd agetag qtag atag stag
+tail    = hashtag type ext
+read    = UNKNOWN_TAG:SD -> sdhd:sd
+read    = mtag:mdrop ->
+write   = mtag:* ->
+write   = type:trailer -> type:.trailer
+
+[grammar]
+entry  = @name [,snen] [,@comment] ,@year [DIV taglist] '.' @type @ext$;
+sdhd   = ('sd'|'hd'|'3d'|'4k') !symbol;
+type   = 'trailer'|'film';
+taglist = [(@sdhd|tags){,(@sdhd|tags)}];
+EONAME = year (DIV|'.'|$);
+DIV    = '__';
+INVALID_TAG = 'asdfafdadf!!';
+`
+
+var tableSchemaFileCorrect = []struct {
+	input, schema, check string
+}{
+	{input: "the_name_2018__hd_16_q0w0.trailer.mp4", schema: "testpartner",
+		check: "the_name_2018__hd_16_q0w0_ar2.trailer.mp4"},
+	{input: "the_name_2018__SD_mdrop.trailer.mp4", schema: "testpartner",
+		check: "the_name_2018__sd_ar2.trailer.mp4"},
+	{input: "the_name_2018__hd_mtest.trailer.mp4", schema: "rt",
+		check: "hd_2018_the_name__ar2_mtest_trailer.mp4"},
+	{input: "sd_2018_the_name__mtest_trailer.mp4", schema: "testpartner",
+		check: "the_name_2018__sd_ar2.trailer.mp4"},
+}
+
+// TestLoadSchemaFile -
+func TestLoadSchemaFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.schema")
+	err := os.WriteFile(path, []byte(testSchemaDefinition), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, err := LoadSchemaFile(path)
+	if err != nil {
+		t.Fatalf("LoadSchemaFile() error: %v", err)
+	}
+	defer delete(globSchemas, schema.Name())
+
+	for _, v := range tableSchemaFileCorrect {
+		tn, err := NewFromString("", v.input, false)
+		if err != nil {
+			t.Errorf("\n%q\nNewFromString() error: %v", v.input, err)
+			continue
+		}
+		res, err := tn.ConvertTo(v.schema)
+		if err != nil {
+			t.Errorf("\n%q\nConvertTo() error: %v", v.input, err)
+			continue
+		}
+		if res != v.check {
+			t.Errorf("\nnot equivalent \nin : %q\nres: %q\nchk: %q", v.input, res, v.check)
+		}
+	}
+}
+
+// TestParseSchemaDefinitionIncorrect -
+func TestParseSchemaDefinitionIncorrect(t *testing.T) {
+	table := []string{
+		"",
+		"name = x",
+		"[grammar]\nentry = 'a';",
+		"name = x\nfilters = unknown\n[grammar]\nentry = 'a';",
+		"name = x\nread = a:b\n[grammar]\nentry = 'a';",
+		"name = x\nwhatever = 1\n[grammar]\nentry = 'a';",
+		"name = x\n[grammar]\nentry = ",
+	}
+	for _, v := range table {
+		_, err := ParseSchemaDefinition(v)
+		if err == nil {
+			t.Errorf("\n%q\nhas no error", v)
+		}
+	}
+}
@@ -1,7 +1,9 @@
 package tagname
 
 import (
+	"strings"
 	"testing"
+	"time"
 )
 
 // TestCorrect -
@@ -52,3 +54,175 @@ filename = process(filename)
 		return
 	}
 }
+
+func TestScriptFuncs(t *testing.T) {
+	src := `
+imed := import("imed")
+
+process := func(path) {
+	if is_error(imed.probe(path)) == false {
+		return error("probe: error expected")
+	}
+	if is_error(imed.gather_atag(path)) == false {
+		return error("gather_atag: error expected")
+	}
+	if imed.lang_by_lat("eng").name != "Английский" {
+		return error("lang_by_lat: " + imed.lang_by_lat("eng"))
+	}
+	if imed.lang_by_name("xxx") != undefined {
+		return error("lang_by_name: undefined expected")
+	}
+	tn := imed.tagname("sd_2019_xxx__film", false)
+	format := tn.describe()
+	if is_error(format) {
+		return format
+	}
+	tn.set_tag("name", imed.translit("Тестовый фильм " + format.resolution))
+	return [tn]
+}
+
+filename = process(filename)
+`
+	s, err := NewScript(src)
+	if err != nil {
+		t.Fatalf("NewScript() error: %v", err)
+	}
+	ret, err := s.Run("/nonexistent/file.mp4")
+	if err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if len(ret) != 1 {
+		t.Fatalf("Run() error: len(ret) != 1 (%v)", len(ret))
+	}
+	if v, _ := ret[0].ConvertTo("old"); v != "testovyy_film_720x576_2019__sd_ar2" {
+		t.Errorf("error: invalid return value %v", v)
+	}
+}
+
+func TestScriptFixtures(t *testing.T) {
+	src := `
+imed := import("imed")
+text := import("text")
+
+main := func() {
+	if text.has_prefix(filename, "panic") {
+		return [1]
+	}
+	tn := imed.tagname(filename, false)
+	if tn.has_err() {
+		return tn.err()
+	}
+	return [tn]
+}
+
+filename = main()
+`
+	fixtures := `
+# comment
+The_Movie_2019__hd_ar6e2.mp4 => hd_2019_the_movie__ar6e2_x1GF40gNCfD_film.mp4
+bad.mp4 => error: unexpected
+bad.mp4 => error
+xxx_2019__sd_ar2.mp4 => sd_2019_xxx__ar2_film.mp4
+bad.mp4 => bad.mp4
+panic.mp4 => error: not Tagname
+`
+	s, err := NewScript(src)
+	if err != nil {
+		t.Fatalf("NewScript() error: %v", err)
+	}
+	cases, err := ReadScriptFixtures(strings.NewReader(fixtures), "test")
+	if err != nil {
+		t.Fatalf("ReadScriptFixtures() error: %v", err)
+	}
+	results := TestScript(s, cases, "rt")
+	want := []bool{true, true, true, false, false, true}
+	if len(results) != len(want) {
+		t.Fatalf("len(results) = %v, want %v", len(results), len(want))
+	}
+	for i, res := range results {
+		if res.OK() != want[i] {
+			t.Errorf("#%v %v: OK() = %v, want %v (actual %v, error %v)", i, res.Case.Source, res.OK(), want[i], res.Actual, res.Err)
+		}
+	}
+	b := &strings.Builder{}
+	if failed := WriteScriptReport(b, results); failed != 2 {
+		t.Errorf("WriteScriptReport() = %v, want 2", failed)
+	}
+	report := b.String()
+	for _, s := range []string{
+		"@@ test:6 xxx_2019__sd_ar2.mp4\n- sd_2019_xxx__ar2_film.mp4\n+ sd_2019_xxx__ar2_",
+		"@@ test:7 bad.mp4\n- bad.mp4\n+ error: ",
+		"4 passed, 2 failed\n",
+	} {
+		if !strings.Contains(report, s) {
+			t.Errorf("report does not contain %q:\n%v", s, report)
+		}
+	}
+
+	if _, err := ReadScriptFixtures(strings.NewReader("no arrow"), "bad"); err == nil {
+		t.Errorf("ReadScriptFixtures() error expected")
+	}
+}
+
+// TestScriptRunErrors - a script that does not return tagnames fails the file, not the program
+func TestScriptRunErrors(t *testing.T) {
+	table := []struct {
+		src, errHas string
+	}{
+		{"filename = undefined", "did not set 'filename'"},
+		{"filename = 5", "must be an array of tagnames"},
+		{`filename = ["x"]`, "not Tagname"},
+	}
+	for _, v := range table {
+		s, err := NewScript(v.src)
+		if err != nil {
+			t.Errorf("%q: NewScript() error: %v", v.src, err)
+			continue
+		}
+		_, err = s.Run("the_name_2018__hd.mp4")
+		if err == nil || !strings.Contains(err.Error(), v.errHas) {
+			t.Errorf("%q: Run() error %v, want %q", v.src, err, v.errHas)
+		}
+	}
+}
+
+func TestScriptOptions(t *testing.T) {
+	type tCase struct {
+		src     string
+		opts    TScriptOptions
+		compile string // a part of the expected compile error
+		run     string // a part of the expected run error
+	}
+	ok := `filename = [import("imed").tagname("sd_2019_xxx__film", false)]`
+	cases := []tCase{
+		{ok, TScriptOptions{}, "", ""},
+		{`os := import("os"); ` + ok, TScriptOptions{}, "module 'os' not found", ""},
+		{`os := import("os"); ` + ok, TScriptOptions{Modules: []string{"imed", "os"}}, "", ""},
+		{`fmt := import("fmt"); ` + ok, TScriptOptions{Modules: []string{"imed"}}, "module 'fmt' not found", ""},
+		{ok, TScriptOptions{Modules: []string{"imed", "xxx"}}, "unknown script module \"xxx\"", ""},
+		{`for {}; ` + ok, TScriptOptions{Timeout: 50 * time.Millisecond}, "", "has run longer than 50ms"},
+		{`a := []; for i := 0; i < 1000; i++ { a = append(a, [i]) }; ` + ok, TScriptOptions{MaxAllocs: 100}, "", "allocated more than 100 objects"},
+		{`a := []; for i := 0; i < 10; i++ { a = append(a, [i]) }; ` + ok, TScriptOptions{MaxAllocs: 1000, Timeout: time.Second}, "", ""},
+	}
+	for i, c := range cases {
+		s, err := NewScriptWithOptions(c.src, c.opts)
+		if c.compile != "" {
+			if err == nil || !strings.Contains(err.Error(), c.compile) {
+				t.Errorf("#%v: compile error %v, want %q", i, err, c.compile)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("#%v: unexpected compile error: %v", i, err)
+			continue
+		}
+		// a clone must keep the limits
+		_, err = s.Clone().Run("test")
+		if c.run == "" && err != nil {
+			t.Errorf("#%v: unexpected run error: %v", i, err)
+		}
+		if c.run != "" && (err == nil || !strings.Contains(err.Error(), c.run)) {
+			t.Errorf("#%v: run error %v, want %q", i, err, c.run)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package tagname
+
+import (
+	"encoding/json"
+
+	"github.com/d5/tengo/v2"
+	"github.com/malashin/ffinfo"
+
+	"github.com/macroblock/imed/pkg/ffcache"
+	"github.com/macroblock/imed/pkg/lang"
+	"github.com/macroblock/imed/pkg/translit"
+)
+
+// Functions of the 'imed' module that look at the file itself and reuse other packages.
+// A function returns an error object instead of failing the script, so a script can
+// check the result with is_error().
+
+// addScriptFuncs - adds probe, gather_*, translit and lang_* functions to the module map
+func addScriptFuncs(m map[string]tengo.Object) {
+	m["probe"] = &tengo.UserFunction{Name: "probe", Value: funcASRO(probeObject)}
+	m["gather_ext"] = &tengo.UserFunction{Name: "gather_ext", Value: funcGather(GatherExtension)}
+	m["gather_format_ext"] = &tengo.UserFunction{Name: "gather_format_ext", Value: funcGather(GatherFormatExtension)}
+	m["gather_sizetag"] = &tengo.UserFunction{Name: "gather_sizetag", Value: funcGather(GatherSizeTag)}
+	m["gather_atag"] = &tengo.UserFunction{Name: "gather_atag", Value: funcGather(GatherATag)}
+	m["gather_stag"] = &tengo.UserFunction{Name: "gather_stag", Value: funcGather(GatherSTag)}
+	m["gather_sdhd"] = &tengo.UserFunction{Name: "gather_sdhd", Value: funcGather(GatherSDHD)}
+	m["translit"] = &tengo.UserFunction{Name: "translit", Value: funcASRO(translitObject)}
+	m["lang_by_name"] = &tengo.UserFunction{Name: "lang_by_name", Value: funcASRO(func(s string) (tengo.Object, error) {
+		return langObject(lang.ByName(s)), nil
+	})}
+	m["lang_by_lat"] = &tengo.UserFunction{Name: "lang_by_lat", Value: funcASRO(func(s string) (tengo.Object, error) {
+		return langObject(lang.ByLat(s)), nil
+	})}
+}
+
+// toObject - converts a value to tengo objects through its JSON representation
+func toObject(v interface{}) (tengo.Object, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var ret interface{}
+	err = json.Unmarshal(data, &ret)
+	if err != nil {
+		return nil, err
+	}
+	return tengo.FromInterface(ret)
+}
+
+// probeObject - ffprobe info of the file as a map with ffprobe names ('format', 'streams', 'codec_type', ...)
+func probeObject(path string) (tengo.Object, error) {
+	info, err := ffcache.Probe(path)
+	if err != nil {
+		return wrapError(err), nil
+	}
+	return toObject(info)
+}
+
+func translitObject(s string) (tengo.Object, error) {
+	ret, err := translit.Do(s)
+	if err != nil {
+		return wrapError(err), nil
+	}
+	return &tengo.String{Value: ret}, nil
+}
+
+// langObject - a language record as a map or undefined if there is no such language
+func langObject(rec *lang.Record) tengo.Object {
+	if rec == nil {
+		return tengo.UndefinedValue
+	}
+	return &tengo.ImmutableMap{Value: map[string]tengo.Object{
+		"name":    &tengo.String{Value: rec.Name},
+		"comment": &tengo.String{Value: rec.Comment},
+		"cyr":     &tengo.String{Value: rec.Cyr},
+		"lat":     &tengo.String{Value: rec.Lat},
+		"code":    &tengo.Int{Value: int64(rec.Code)},
+	}}
+}
+
+// fnProbe - ffprobe info of the tagname file
+func (o *tnType) fnProbe(args ...tengo.Object) (tengo.Object, error) {
+	if o == nil || o.tn == nil {
+		return nil, ErrTagnameIsNil
+	}
+	if len(args) != 0 {
+		return nil, tengo.ErrWrongNumArguments
+	}
+	info, err := o.tn.FFInfo()
+	if err != nil {
+		o.setError(err)
+		return wrapError(err), nil
+	}
+	return toObject(info)
+}
+
+// fnDescribe - the Describe() format of the tagname as a map (see (*TFormat).MarshalJSON)
+func (o *tnType) fnDescribe(args ...tengo.Object) (tengo.Object, error) {
+	if o == nil || o.tn == nil {
+		return nil, ErrTagnameIsNil
+	}
+	if len(args) != 0 {
+		return nil, tengo.ErrWrongNumArguments
+	}
+	format, err := o.tn.Describe()
+	if err != nil {
+		o.setError(err)
+		return wrapError(err), nil
+	}
+	return toObject(format)
+}
+
+func funcASRO(fn func(string) (tengo.Object, error)) tengo.CallableFunc {
+	return func(args ...tengo.Object) (tengo.Object, error) {
+		if len(args) != 1 {
+			return nil, tengo.ErrWrongNumArguments
+		}
+		val := args[0]
+		s1, ok := tengo.ToString(val)
+		if !ok {
+			return nil, tengo.ErrInvalidArgumentType{
+				Name:     "first",
+				Expected: "string(compatible)",
+				Found:    val.TypeName(),
+			}
+		}
+		return fn(s1)
+	}
+}
+
+// funcGather - calls the gather function with ffprobe info of the file given by path
+func funcGather(fn func(*ffinfo.File) (string, error)) tengo.CallableFunc {
+	return funcASRO(func(path string) (tengo.Object, error) {
+		info, err := ffcache.Probe(path)
+		if err != nil {
+			return wrapError(err), nil
+		}
+		ret, err := fn(info)
+		if err != nil {
+			return wrapError(err), nil
+		}
+		return &tengo.String{Value: ret}, nil
+	})
+}
@@ -1,10 +1,14 @@
 package tagname
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/d5/tengo/v2"
 	"github.com/d5/tengo/v2/stdlib"
@@ -14,18 +18,59 @@ var ErrTagnameIsNil = fmt.Errorf("Tagname object is <nil>")
 
 type TScript struct {
 	compiled *tengo.Compiled
+	opts     TScriptOptions
 }
 
+// TScriptOptions - limits of a script
+type TScriptOptions struct {
+	// Modules - modules the script can import ('imed' and tengo stdlib names).
+	// DefaultScriptModules are used if it is empty.
+	Modules []string
+	// Timeout - max duration of a single run, 0 means no limit
+	Timeout time.Duration
+	// MaxAllocs - max number of objects allocated by a single run, 0 means no limit
+	MaxAllocs int64
+}
+
+// DefaultScriptModules - all the modules except 'os', so a script cannot touch the file system
+var DefaultScriptModules = []string{"imed", "fmt", "text", "math", "times", "rand", "json", "base64", "hex", "enum"}
+
+// ScriptModules - names of all the modules a script can be allowed to import
+func ScriptModules() []string {
+	ret := append([]string{"imed"}, stdlib.AllModuleNames()...)
+	sort.Strings(ret[1:])
+	return ret
+}
+
+// NewScript - compiles the script with DefaultScriptModules and without limits
 func NewScript(src string) (*TScript, error) {
+	return NewScriptWithOptions(src, TScriptOptions{})
+}
+
+// NewScriptWithOptions -
+func NewScriptWithOptions(src string, opts TScriptOptions) (*TScript, error) {
 	script := tengo.NewScript([]byte(src))
 
+	modules := opts.Modules
+	if len(modules) == 0 {
+		modules = DefaultScriptModules
+	}
 	moduleMap := tengo.NewModuleMap()
-	moduleMap.AddMap(stdlib.GetModuleMap(stdlib.AllModuleNames()...))
-
-	tnType := newTnModType()
-	moduleMap.AddBuiltinModule("imed", tnType.ModuleMap())
-
+	for _, name := range modules {
+		switch {
+		case name == "imed":
+			tnType := newTnModType()
+			moduleMap.AddBuiltinModule("imed", tnType.ModuleMap())
+		case stdlib.BuiltinModules[name] != nil || stdlib.SourceModules[name] != "":
+			moduleMap.AddMap(stdlib.GetModuleMap(name))
+		default:
+			return nil, fmt.Errorf("unknown script module %q (available: %v)", name, strings.Join(ScriptModules(), ", "))
+		}
+	}
 	script.SetImports(moduleMap)
+	if opts.MaxAllocs > 0 {
+		script.SetMaxAllocs(opts.MaxAllocs)
+	}
 
 	err := script.Add("filename", "###error###")
 	if err != nil {
@@ -37,7 +82,12 @@ func NewScript(src string) (*TScript, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &TScript{compiled}, err
+	return &TScript{compiled, opts}, err
+}
+
+// Clone - returns a copy of the script that can be run concurrently with the original one
+func (o *TScript) Clone() *TScript {
+	return &TScript{o.compiled.Clone(), o.opts}
 }
 
 func (o *TScript) Run(arg string) ([]*TTagname, error) {
@@ -45,13 +95,13 @@ func (o *TScript) Run(arg string) ([]*TTagname, error) {
 	if err != nil {
 		return nil, err
 	}
-	err = o.compiled.Run()
+	err = o.runContext()
 	if err != nil {
 		return nil, err
 	}
 	v := o.compiled.Get("filename")
-	if v == nil {
-		panic("!!!")
+	if v == nil || v.IsUndefined() {
+		return nil, fmt.Errorf("script did not set 'filename'")
 	}
 
 	if err, ok := v.Value().(error); ok {
@@ -60,7 +110,7 @@ func (o *TScript) Run(arg string) ([]*TTagname, error) {
 
 	arr, ok := v.Value().([]interface{})
 	if !ok {
-		panic("???")
+		return nil, fmt.Errorf("'filename' must be an array of tagnames, have %T", v.Value())
 	}
 	ret := []*TTagname(nil)
 
@@ -83,6 +133,24 @@ func (o *TScript) Run(arg string) ([]*TTagname, error) {
 	return ret, err
 }
 
+// runContext - runs the script within the limits of the options
+func (o *TScript) runContext() error {
+	ctx := context.Background()
+	if o.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.opts.Timeout)
+		defer cancel()
+	}
+	err := o.compiled.RunContext(ctx)
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return fmt.Errorf("script aborted: it has run longer than %v", o.opts.Timeout)
+	case errors.Is(err, tengo.ErrObjectAllocLimit):
+		return fmt.Errorf("script aborted: it has allocated more than %v objects", o.opts.MaxAllocs)
+	}
+	return err
+}
+
 type tnModType struct {
 	tengo.ObjectImpl
 	moduleMap map[string]tengo.Object
@@ -99,6 +167,7 @@ func newTnModType() *tnModType {
 		"fileext":  &tengo.UserFunction{Value: funcASRS(filepath.Ext)},
 		// "err": &tengo.UserFunction{Value: ret.fnError},
 	}
+	addScriptFuncs(ret.moduleMap)
 	return ret
 }
 
@@ -219,6 +288,12 @@ func (o *tnType) IndexGet(index tengo.Object) (tengo.Object, error) {
 		return &tengo.UserFunction{Value: funcRSE(o, o.tn.GatherSTag)}, nil
 	case "gathersdhd", "gather_sdhd":
 		return &tengo.UserFunction{Value: funcRSE(o, o.tn.GatherSDHD)}, nil
+	case "gatherformatextension", "gatherformatext", "gather_format_extension", "gather_format_ext":
+		return &tengo.UserFunction{Value: funcRSE(o, o.tn.GatherFormatExtension)}, nil
+	case "probe":
+		return &tengo.UserFunction{Value: o.fnProbe}, nil
+	case "describe":
+		return &tengo.UserFunction{Value: o.fnDescribe}, nil
 
 	case "rtimgcheck", "rtimg_check":
 		return &tengo.UserFunction{Value: funcASRE(o, o.tn.RtimgCheck)}, nil
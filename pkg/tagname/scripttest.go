@@ -0,0 +1,154 @@
+package tagname
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// A script fixture file has one case per line:
+//
+//	# a comment
+//	input_filename => expected_output
+//	input_filename => first_output | second_output
+//	input_filename => error
+//	input_filename => error: a part of the expected error message
+//	input_filename =>
+//
+// An empty expectation means the script returns no tagnames.
+
+// TScriptCase - a single case of a script fixture
+type TScriptCase struct {
+	Source   string // a fixture file name and a line number
+	Input    string
+	Expected []string
+	IsError  bool
+	ErrorHas string
+}
+
+// TScriptResult -
+type TScriptResult struct {
+	Case   *TScriptCase
+	Actual []string
+	Err    error
+}
+
+// OK - the result matches the expectation of the case
+func (o *TScriptResult) OK() bool {
+	if o.Case.IsError {
+		return o.Err != nil && strings.Contains(o.Err.Error(), o.Case.ErrorHas)
+	}
+	if o.Err != nil || len(o.Actual) != len(o.Case.Expected) {
+		return false
+	}
+	for i := range o.Actual {
+		if o.Actual[i] != o.Case.Expected[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// LoadScriptFixtures - reads cases from the fixture file
+func LoadScriptFixtures(path string) ([]*TScriptCase, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadScriptFixtures(f, path)
+}
+
+// ReadScriptFixtures - reads cases from r, name is used in messages
+func ReadScriptFixtures(r io.Reader, name string) ([]*TScriptCase, error) {
+	ret := []*TScriptCase{}
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		input, expected, ok := strings.Cut(line, "=>")
+		input = strings.TrimSpace(input)
+		if !ok || input == "" {
+			return nil, fmt.Errorf("%v:%v: want 'input => expected'", name, lineNo)
+		}
+		c := &TScriptCase{Source: fmt.Sprintf("%v:%v", name, lineNo), Input: input, Expected: []string{}}
+		expected = strings.TrimSpace(expected)
+		switch {
+		case expected == "error":
+			c.IsError = true
+		case strings.HasPrefix(expected, "error:"):
+			c.IsError = true
+			c.ErrorHas = strings.TrimSpace(strings.TrimPrefix(expected, "error:"))
+		case expected != "":
+			for _, s := range strings.Split(expected, "|") {
+				c.Expected = append(c.Expected, strings.TrimSpace(s))
+			}
+		}
+		ret = append(ret, c)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// TestScript - runs the script on the input of every case and converts the result to
+// the schema (the own schema of a tagname if it is empty)
+func TestScript(script *TScript, cases []*TScriptCase, schema string) []*TScriptResult {
+	ret := []*TScriptResult{}
+	for _, c := range cases {
+		res := &TScriptResult{Case: c, Actual: []string{}}
+		list, err := script.Run(c.Input)
+		for _, tn := range list {
+			if err != nil {
+				break
+			}
+			var s string
+			s, err = tn.ConvertTo(schema)
+			res.Actual = append(res.Actual, s)
+		}
+		res.Err = err
+		ret = append(ret, res)
+	}
+	return ret
+}
+
+// WriteScriptReport - writes a diff of the failed cases and a summary. It returns the number of failed cases.
+func WriteScriptReport(w io.Writer, results []*TScriptResult) int {
+	failed := 0
+	for _, res := range results {
+		if res.OK() {
+			continue
+		}
+		failed++
+		c := res.Case
+		fmt.Fprintf(w, "@@ %v %v\n", c.Source, c.Input)
+		switch {
+		case c.IsError && c.ErrorHas != "":
+			fmt.Fprintf(w, "- error: %v\n", c.ErrorHas)
+		case c.IsError:
+			fmt.Fprintf(w, "- error\n")
+		default:
+			for _, s := range c.Expected {
+				fmt.Fprintf(w, "- %v\n", s)
+			}
+		}
+		if res.Err != nil {
+			for _, s := range strings.Split(res.Err.Error(), "\n") {
+				fmt.Fprintf(w, "+ error: %v\n", s)
+			}
+		} else {
+			for _, s := range res.Actual {
+				fmt.Fprintf(w, "+ %v\n", s)
+			}
+		}
+	}
+	fmt.Fprintf(w, "%v passed, %v failed\n", len(results)-failed, failed)
+	return failed
+}
@@ -0,0 +1,505 @@
+package tagname
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TSuggestion -
+type TSuggestion struct {
+	Name  string
+	Cost  int
+	Fixes []string
+}
+
+const (
+	suggestMaxDepth   = 3
+	suggestBeamWidth  = 16
+	suggestMaxResults = 10
+	suggestMaxDist    = 2
+	suggestDeleteCost = 3
+)
+
+// vocabulary rules of the grammar and prefixes that are added to their literals
+var vocabularyRules = []struct {
+	rule, prefix string
+}{
+	{"vtag", "v"},
+	{"EXCLUSIVE_TAGS", ""},
+	{"agetag", ""},
+	{"sdhd", ""},
+	{"type", ""},
+	{"hardsubtag", ""},
+	{"smktag", ""},
+	{"alcotag", ""},
+	{"sbstag", ""},
+	{"aligntag", ""},
+}
+
+// tag types that are produced by the parser for a malformed tag of the type
+var malformedTagTypes = map[string]string{
+	"ERR_atag":    "atag",
+	"ERR_agetag":  "agetag",
+	"vtag":        "vtag",
+	"UNKNOWN_TAG": "",
+}
+
+var defaultATags = []string{"ar2", "ar6", "ae2", "ae6", "ar2e2", "ar6e2", "ar2e6", "ar6e6"}
+
+var (
+	reLiteral = regexp.MustCompile(`'([^']*)'`)
+	reSnen    = regexp.MustCompile(`^[sS](\d{1,2})[eE](\d{1,3})([ab]?)((?:(?:[-+][eE]?|[eE])\d{1,3}[ab]?)*)$`)
+	reEpRest  = regexp.MustCompile(`(?:([-+])[eE]?|[eE])(\d{1,3})([ab]?)`)
+	reSeason  = regexp.MustCompile(`^[sS](\d{1,2})$`)
+	reEpisode = regexp.MustCompile(`^[eE](\d{1,3})([ab]?)$`)
+)
+
+// grammarLiterals - returns quoted literals of the rule
+func grammarLiterals(grammar, rule string) []string {
+	re := regexp.MustCompile(`(?m)^\s*` + regexp.QuoteMeta(rule) + `\s*=([^;]*);`)
+	m := re.FindStringSubmatch(grammar)
+	if m == nil {
+		return nil
+	}
+	ret := []string{}
+	for _, lit := range reLiteral.FindAllStringSubmatch(m[1], -1) {
+		ret = append(ret, lit[1])
+	}
+	return ret
+}
+
+type tVocabItem struct {
+	val, typ string
+}
+
+func (o *TSchema) vocabulary() []tVocabItem {
+	ret := []tVocabItem{}
+	for _, v := range vocabularyRules {
+		for _, lit := range grammarLiterals(spliceVocabulary(o.grammar), v.rule) {
+			if len(lit) < 2 {
+				continue
+			}
+			ret = append(ret, tVocabItem{v.prefix + lit, v.rule})
+		}
+	}
+	for _, v := range defaultATags {
+		ret = append(ret, tVocabItem{v, "atag"})
+	}
+	return ret
+}
+
+// Vocabulary - returns the known tag values of the schema grammar
+func (o *TSchema) Vocabulary() []string {
+	seen := map[string]bool{}
+	ret := []string{}
+	for _, v := range o.vocabulary() {
+		if seen[v.val] {
+			continue
+		}
+		seen[v.val] = true
+		ret = append(ret, v.val)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min3(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// tToken - a part of the filename with the separator placed before it
+type tToken struct {
+	sep, val string
+}
+
+func tokenize(s string) []tToken {
+	ret := []tToken{}
+	sep := ""
+	val := ""
+	for _, r := range s {
+		if r == '_' || r == '.' {
+			if val != "" {
+				ret = append(ret, tToken{sep, val})
+				sep = ""
+				val = ""
+			}
+			sep += string(r)
+			continue
+		}
+		val += string(r)
+	}
+	ret = append(ret, tToken{sep, val})
+	return ret
+}
+
+func untokenize(list []tToken) string {
+	ret := ""
+	for _, t := range list {
+		ret += t.sep + t.val
+	}
+	return ret
+}
+
+type tCandidate struct {
+	str   string
+	cost  int
+	fixes []string
+}
+
+type tEdit struct {
+	tokens []tToken
+	cost   int
+	fix    string
+}
+
+func snenTokens(val string) []string {
+	m := reSnen.FindStringSubmatch(val)
+	if m == nil {
+		return nil
+	}
+	s, _ := strconv.Atoi(m[1])
+	e, _ := strconv.Atoi(m[2])
+	exx := fmt.Sprintf("%02d%v", e, m[3])
+	// 's01e01e02' and 's01e01-e02' are lists and ranges of episodes
+	for _, r := range reEpRest.FindAllStringSubmatch(m[4], -1) {
+		sep := r[1]
+		if sep == "" {
+			sep = "+"
+		}
+		e, _ = strconv.Atoi(r[2])
+		exx += fmt.Sprintf("%v%02d%v", sep, e, r[3])
+	}
+	return []string{fmt.Sprintf("s%02d", s), normalizeExx(exx)}
+}
+
+// normalizeSnen - replaces 'sXXeYY' tokens with 'sXX_YY' ones
+func normalizeSnen(list []tToken) ([]tToken, []string) {
+	ret := []tToken{}
+	fixes := []string{}
+	for _, t := range list {
+		vals := snenTokens(t.val)
+		if vals == nil {
+			ret = append(ret, t)
+			continue
+		}
+		fixes = append(fixes, fmt.Sprintf("%q -> %q", t.val, strings.Join(vals, "_")))
+		ret = append(ret, tToken{t.sep, vals[0]}, tToken{"_", vals[1]})
+	}
+	return ret, fixes
+}
+
+func isTagLike(s string, known map[string]bool) bool {
+	return known[s] || reSnen.MatchString(s) || (len(s) == 2 && isDigits(s)) ||
+		(strings.HasPrefix(s, "a") && strings.ContainsAny(s, "0123456789"))
+}
+
+// tokenEdits - hints maps a malformed token to the type of the tag it was parsed as
+func tokenEdits(list []tToken, vocab []tVocabItem, hints map[string]string) []tEdit {
+	ret := []tEdit{}
+	replace := func(i int, vals []string, cost int, fix string) {
+		tokens := append([]tToken(nil), list...)
+		tokens[i].val = vals[0]
+		for _, v := range vals[1:] {
+			tokens = append(tokens[:i+1], append([]tToken{{"_", v}}, tokens[i+1:]...)...)
+			i++
+		}
+		ret = append(ret, tEdit{tokens, cost, fix})
+	}
+	known := map[string]bool{}
+	for _, v := range vocab {
+		known[v.val] = true
+	}
+	// tags can be moved only to the tag list that starts with '__'
+	div := 0
+	for i, t := range list {
+		if t.sep == "__" {
+			div = i
+			break
+		}
+	}
+	for i, t := range list {
+		// keep the extension and the empty tokens
+		if t.val == "" || (i == len(list)-1 && t.sep == ".") {
+			continue
+		}
+		if vals := snenTokens(t.val); vals != nil {
+			replace(i, vals, 1, fmt.Sprintf("%q -> %q", t.val, strings.Join(vals, "_")))
+			continue
+		}
+		if m := reSeason.FindStringSubmatch(t.val); m != nil {
+			s, _ := strconv.Atoi(m[1])
+			if sxx := fmt.Sprintf("s%02d", s); sxx != t.val {
+				replace(i, []string{sxx}, 1, fmt.Sprintf("%q -> %q", t.val, sxx))
+			}
+		}
+		if m := reEpisode.FindStringSubmatch(t.val); m != nil {
+			e, _ := strconv.Atoi(m[1])
+			exx := fmt.Sprintf("%02d%v", e, m[2])
+			replace(i, []string{exx}, 1, fmt.Sprintf("%q -> %q", t.val, exx))
+		}
+		// move a tag to another place
+		if isTagLike(t.val, known) {
+			for j := range list {
+				if j == i || j == i-1 || j == len(list)-1 || j < div {
+					continue
+				}
+				tokens := append([]tToken(nil), list[:i]...)
+				tokens = append(tokens, list[i+1:]...)
+				k := j
+				if j > i {
+					k--
+				}
+				sep := "_"
+				if tokens[k].sep == "" && k == 0 {
+					sep = ""
+				}
+				tokens = append(tokens[:k+1], append([]tToken{{sep, t.val}}, tokens[k+1:]...)...)
+				if i == 0 {
+					tokens[0].sep = ""
+				}
+				ret = append(ret, tEdit{tokens, 1, fmt.Sprintf("move %q", t.val)})
+			}
+		}
+		if known[t.val] {
+			continue
+		}
+		hint, hasHint := hints[t.val]
+		lower := strings.ToLower(t.val)
+		for _, v := range vocab {
+			if isDigits(v.val) != isDigits(t.val) {
+				continue
+			}
+			dist := editDistance(lower, v.val)
+			if dist > suggestMaxDist || dist >= len([]rune(v.val)) {
+				continue
+			}
+			if lower != t.val && dist == 0 {
+				dist = 1
+			}
+			if isDigits(v.val) {
+				// prefer the numerically nearest value
+				a, _ := strconv.Atoi(t.val)
+				b, _ := strconv.Atoi(v.val)
+				if a-b > 2 || b-a > 2 {
+					dist++
+				}
+			}
+			if hasHint && hint != "" && hint != v.typ {
+				dist++
+			}
+			replace(i, []string{v.val}, dist, fmt.Sprintf("%q -> %q", t.val, v.val))
+		}
+		// delete the token
+		tokens := append([]tToken(nil), list[:i]...)
+		tokens = append(tokens, list[i+1:]...)
+		if i == 0 && len(tokens) > 0 {
+			tokens[0].sep = ""
+		} else if i+1 < len(list) && len(list[i+1].sep) < len(t.sep) {
+			tokens[i].sep = t.sep
+		}
+		ret = append(ret, tEdit{tokens, suggestDeleteCost, fmt.Sprintf("remove %q", t.val)})
+	}
+	return ret
+}
+
+// malformedHints - returns the malformed tokens of the string with the types of the tags they were parsed as
+func malformedHints(str string, schemas []string) map[string]string {
+	ret := map[string]string{}
+	for _, name := range schemas {
+		tags, err := Parse(str, name)
+		if err != nil {
+			continue
+		}
+		for typ, list := range tags.byType {
+			hint, ok := malformedTagTypes[typ]
+			if !ok {
+				continue
+			}
+			for _, val := range list {
+				ret[val] = hint
+			}
+		}
+		break
+	}
+	return ret
+}
+
+// Suggest - returns ranked candidate names that parse and pass the check under the schema.
+// If schemaName is empty the schema of the parsed candidate is used.
+func Suggest(str string, schemaName string) ([]*TSuggestion, error) {
+	var schemas []string
+	if schemaName != "" {
+		if _, err := Schema(schemaName); err != nil {
+			return nil, err
+		}
+		schemas = append(schemas, schemaName)
+	}
+	for _, name := range defaultSchemaOrder() {
+		if name != schemaName {
+			schemas = append(schemas, name)
+		}
+	}
+	vocab := []tVocabItem{}
+	seen := map[tVocabItem]bool{}
+	for _, name := range schemas {
+		schema, _ := Schema(name)
+		for _, v := range schema.vocabulary() {
+			if !seen[v] {
+				seen[v] = true
+				vocab = append(vocab, v)
+			}
+		}
+	}
+
+	results := map[string]*TSuggestion{}
+	addResult := func(name string, cost int, fixes []string) {
+		if old, ok := results[name]; ok && old.Cost <= cost {
+			return
+		}
+		results[name] = &TSuggestion{Name: name, Cost: cost, Fixes: fixes}
+	}
+	verify := func(c tCandidate) {
+		tn, err := NewFromString("", c.str, false, schemas...)
+		if tn == nil {
+			return
+		}
+		target := schemaName
+		if target == "" {
+			target = tn.Schema()
+		}
+		if err == nil {
+			if name, err := tn.ConvertTo(target); err == nil && isValidName(name, target) {
+				fixes := c.fixes
+				if tn.Schema() != target {
+					fixes = append(append([]string(nil), fixes...), fmt.Sprintf("convert to %q", target))
+				}
+				addResult(name, c.cost, fixes)
+			}
+		}
+	}
+	// the number of problems of the parsed candidate
+	score := func(str string) int {
+		tn, err := NewFromString("", str, false, schemas...)
+		if tn == nil {
+			return 1 << 16
+		}
+		return len(Diagnostics(err).Errors())
+	}
+
+	beam := []tCandidate{{str: str}}
+	visited := map[string]bool{str: true}
+	// names like 'name_s01e05_2018' are valid but the episode becomes a part of the name
+	if tokens, fixes := normalizeSnen(tokenize(str)); len(fixes) > 0 {
+		s := untokenize(tokens)
+		visited[s] = true
+		beam = append(beam, tCandidate{s, len(fixes), fixes})
+	}
+	for depth := 0; len(beam) > 0; depth++ {
+		for _, c := range beam {
+			verify(c)
+		}
+		if len(results) > 0 || depth == suggestMaxDepth {
+			break
+		}
+		type tScored struct {
+			tCandidate
+			score int
+		}
+		next := []tScored{}
+		for _, c := range beam {
+			hints := malformedHints(c.str, schemas)
+			for _, e := range tokenEdits(tokenize(c.str), vocab, hints) {
+				s := untokenize(e.tokens)
+				if visited[s] {
+					continue
+				}
+				visited[s] = true
+				fixes := append(append([]string(nil), c.fixes...), e.fix)
+				next = append(next, tScored{tCandidate{s, c.cost + e.cost, fixes}, score(s)})
+			}
+		}
+		sort.SliceStable(next, func(i, j int) bool {
+			if next[i].score != next[j].score {
+				return next[i].score < next[j].score
+			}
+			return next[i].cost < next[j].cost
+		})
+		beam = beam[:0]
+		for i := 0; i < len(next) && i < suggestBeamWidth; i++ {
+			beam = append(beam, next[i].tCandidate)
+		}
+	}
+
+	ret := make([]*TSuggestion, 0, len(results))
+	for _, v := range results {
+		ret = append(ret, v)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Cost != ret[j].Cost {
+			return ret[i].Cost < ret[j].Cost
+		}
+		return ret[i].Name < ret[j].Name
+	})
+	if len(ret) > suggestMaxResults {
+		ret = ret[:suggestMaxResults]
+	}
+	return ret, nil
+}
+
+func isValidName(name, schemaName string) bool {
+	tags, err := Parse(name, schemaName)
+	if err != nil {
+		return false
+	}
+	schema, err := Schema(schemaName)
+	if err != nil {
+		return false
+	}
+	tags, err = TranslateTags(tags, schema.UnmarshallFilter)
+	if err != nil {
+		return false
+	}
+	return CheckTags(tags) == nil
+}
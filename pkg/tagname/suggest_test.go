@@ -0,0 +1,45 @@
+package tagname
+
+import (
+	"testing"
+)
+
+var tableSuggest = []struct {
+	input, schema, check string
+}{
+	{input: "the_name_2018__hd_vgoblinn.trailer.mp4",
+		check: "the_name_2018__hd_ar2_vgoblin.trailer.mp4"},
+	{input: "the_name_2018__hd_disnye.trailer.mp4",
+		check: "the_name_2018__hd_ar2_mdisney.trailer.mp4"},
+	{input: "the_name_hd_2018__16.trailer.mp4",
+		check: "the_name_2018__hd_16_ar2.trailer.mp4"},
+	{input: "hd_2018_the_name__ar2_trailer_16.mp4",
+		check: "hd_2018_the_name__16_ar2_trailer.mp4"},
+	{input: "the_name_S01E05_2018__hd_16.trailer.mp4", schema: "rt",
+		check: "hd_2018_the_name_s01_05__16_ar2_trailer.mp4"},
+	{input: "sd_2018_the_name__ar2_trailer.mp4",
+		check: "sd_2018_the_name__ar2_trailer.mp4"},
+}
+
+// TestSuggest -
+func TestSuggest(t *testing.T) {
+	for _, v := range tableSuggest {
+		list, err := Suggest(v.input, v.schema)
+		if err != nil {
+			t.Errorf("\n%q\nSuggest() error: %v", v.input, err)
+			continue
+		}
+		found := false
+		for _, s := range list {
+			if _, err := NewFromString("", s.Name, false); err != nil {
+				t.Errorf("\n%q\nsuggestion %q is invalid: %v", v.input, s.Name, err)
+			}
+			if s.Name == v.check {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("\n%q\nsuggestion %q not found in %v", v.input, v.check, list)
+		}
+	}
+}
@@ -1,5 +1,10 @@
 package tagname
 
+import (
+	"fmt"
+	"strings"
+)
+
 // Tag - nil means any value
 type Tag struct {
 	Type  *string
@@ -7,3 +12,53 @@ type Tag struct {
 }
 
 // NewTag - format: typ:val . 'typ' and 'val' can be any string value except '*' that means any string.
+func NewTag(s string) (*Tag, error) {
+	typ, val, ok := strings.Cut(s, ":")
+	if !ok {
+		return nil, fmt.Errorf("invalid tag format %q (want 'typ:val')", s)
+	}
+	ret := &Tag{}
+	typ = strings.TrimSpace(typ)
+	val = strings.TrimSpace(val)
+	if typ != "*" {
+		ret.Type = &typ
+	}
+	if val != "*" {
+		ret.Value = &val
+	}
+	return ret, nil
+}
+
+// Match -
+func (o *Tag) Match(typ, val string) bool {
+	if o.Type != nil && *o.Type != typ {
+		return false
+	}
+	if o.Value != nil && *o.Value != val {
+		return false
+	}
+	return true
+}
+
+// Apply - returns typ and val replaced by the tag's non-nil fields.
+func (o *Tag) Apply(typ, val string) (string, string) {
+	if o.Type != nil {
+		typ = *o.Type
+	}
+	if o.Value != nil {
+		val = *o.Value
+	}
+	return typ, val
+}
+
+// String -
+func (o *Tag) String() string {
+	typ, val := "*", "*"
+	if o.Type != nil {
+		typ = *o.Type
+	}
+	if o.Value != nil {
+		val = *o.Value
+	}
+	return typ + ":" + val
+}
@@ -3,11 +3,11 @@ package tagname
 import (
 	"fmt"
 	"path/filepath"
-	"strconv"
 	"strings"
 
 	"github.com/malashin/ffinfo"
 
+	"github.com/macroblock/imed/pkg/ffcache"
 	"github.com/macroblock/imed/pkg/zlog/zlog"
 	"github.com/macroblock/rtimg/pkg"
 )
@@ -24,7 +24,15 @@ type TTagname struct {
 	srcTags    *TTags
 	tags       *TTags
 
-	internalInfo *ffinfo.File
+	internalInfo  *ffinfo.File
+	internalImage *TImageInfo
+	lastCheck     *tCheckResult
+}
+
+// tCheckResult - a result of the last Diagnose call
+type tCheckResult struct {
+	isDeep bool
+	diags  TDiagnostics
 }
 
 // NewFromString -
@@ -32,11 +40,11 @@ func NewFromString(dir string, str string, isDeepCheck bool, schemaNames ...stri
 	var err error
 	var srcTags *TTags
 	var schemaName string
-	var errors []string
+	var errors TParseErrors
 
 	schemas := schemaNames
 	if len(schemas) == 0 {
-		schemas = []string{"rt", "old"}
+		schemas = defaultSchemaOrder()
 	}
 
 	for _, schemaName = range schemas {
@@ -44,12 +52,11 @@ func NewFromString(dir string, str string, isDeepCheck bool, schemaNames ...stri
 		if err == nil {
 			break
 		}
-		errors = append(errors, fmt.Sprintf("%-16q: %v", schemaName, err))
+		errors = append(errors, err)
 	}
 
 	if err != nil {
-		s := strings.Join(errors, "\n  ")
-		return nil, fmt.Errorf("multiple ones:\n  %v", s)
+		return nil, errors
 	}
 
 	tn := &TTagname{schemaName: schemaName, dir: dir, src: str, srcTags: srcTags}
@@ -88,6 +95,17 @@ func NewFromFilename(path string, isDeepCheck bool, schemaNames ...string) (*TTa
 	return ret, err
 }
 
+// defaultSchemaOrder - built-in schemas first, then the loaded ones
+func defaultSchemaOrder() []string {
+	ret := []string{"rt", "old"}
+	for _, name := range Schemas() {
+		if name != "rt" && name != "old" {
+			ret = append(ret, name)
+		}
+	}
+	return ret
+}
+
 func (o *TTagname) findSchema(schemaName string) (*TSchema, error) {
 	if schemaName == "" {
 		schemaName = o.schemaName
@@ -140,6 +158,7 @@ func (o *TTagname) SetSource(path string) {
 	o.src = src
 	o.dir = dir
 	o.internalInfo = nil
+	o.internalImage = nil
 }
 
 // FFInfo -
@@ -152,7 +171,7 @@ func (o *TTagname) FFInfo() (*ffinfo.File, error) {
 	}
 
 	filePath := filepath.Join(o.dir, o.src)
-	info, err := ffinfo.Probe(filePath)
+	info, err := ffcache.Probe(filePath)
 	if err != nil {
 		return nil, err
 	}
@@ -160,18 +179,40 @@ func (o *TTagname) FFInfo() (*ffinfo.File, error) {
 	return info, nil
 }
 
-// Check -
+// ImageInfo - properties of the poster image read without ffprobe
+func (o *TTagname) ImageInfo() (*TImageInfo, error) {
+	if err := o.State(); err != nil {
+		return nil, err
+	}
+	if o.internalImage != nil {
+		return o.internalImage, nil
+	}
+	info, err := InspectImage(filepath.Join(o.dir, o.src))
+	if err != nil {
+		return nil, err
+	}
+	o.internalImage = info
+	return info, nil
+}
+
+// Check - returns TDiagnostics as an error if there are any problems
 func (o *TTagname) Check(isDeepCheck bool) error {
+	return o.Diagnose(isDeepCheck).Err()
+}
+
+// Diagnose - returns all the problems including warnings
+func (o *TTagname) Diagnose(isDeepCheck bool) TDiagnostics {
 	if err := o.State(); err != nil {
-		return err
+		return Diagnostics(err)
 	}
 
-	err := CheckTags(o.tags)
-	if err != nil || !isDeepCheck {
-		return err
+	list := checkTags(o.tags)
+	if len(list.Errors()) == 0 && isDeepCheck {
+		list = append(list, checkDeep(o)...)
 	}
-	err = checkDeep(o)
-	return err
+	list = o.locate(list)
+	o.lastCheck = &tCheckResult{isDeep: isDeepCheck, diags: list}
+	return list
 }
 
 // ListTags -
@@ -270,30 +311,36 @@ type TQuality struct {
 	CacheType  int
 }
 
-// GetQuality -
+// GetQuality - parses 'q<quality>(w|s)<cache type>' tag, e.g. 'q1w0'
 func (o *TTagname) GetQuality() (*TQuality, error) {
 	q, err := o.GetTag("qtag")
 	if err != nil {
 		return nil, fmt.Errorf("qtag is absent")
 	}
-	quality, err := strconv.Atoi(string(q[1]))
-	if err != nil {
-		panic(err)
-	}
-	cachetype, err := strconv.Atoi(string(q[3]))
-	if err != nil {
-		panic(err)
+	if len(q) != 4 || q[0] != 'q' || !isDigit(q[1]) || !isDigit(q[3]) {
+		return nil, fmt.Errorf("invalid qtag %q", q)
 	}
 	wide := false
 	switch q[2] {
 	default:
-		panic("unreachable")
+		return nil, fmt.Errorf("invalid qtag %q (want 'w' or 's' at the 3rd position)", q)
 	case 'w':
 		wide = true
 	case 's':
 		wide = false
 	}
-	return &TQuality{Quality: quality, Widescreen: wide, CacheType: cachetype}, nil
+	return &TQuality{Quality: int(q[1] - '0'), Widescreen: wide, CacheType: int(q[3] - '0')}, nil
+}
+
+// isPalMaster - qtag of an sd tagname describes a PAL master
+func (o *TTagname) isPalMaster() bool {
+	frm, _ := o.GetFormat()
+	_, err := o.GetQuality()
+	return frm == "sd" && err == nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
 }
 
 // TAudio -
@@ -313,7 +360,7 @@ func (o *TTagname) GetAudio() ([]TAudio, error) {
 			return nil, fmt.Errorf("%v", "cannot get audio tag (fomat or/and type tags are missing)")
 		}
 
-		if typ != "film" && typ != "trailer" {
+		if typ != "film" && typ != "trailer" && typ != "extra" {
 			return nil, fmt.Errorf("%v", "cannot get audio tag (fomat or/and type tags are missing)")
 		}
 		val = "ar2"
@@ -321,6 +368,9 @@ func (o *TTagname) GetAudio() ([]TAudio, error) {
 			val = "ar6"
 		}
 	}
+	if len(val) < 2 || val[0] != 'a' {
+		return nil, fmt.Errorf("invalid atag %q", val)
+	}
 	ret := []TAudio{}
 	lang := ""
 	for _, r := range val[1:] {
@@ -328,10 +378,10 @@ func (o *TTagname) GetAudio() ([]TAudio, error) {
 			lang += string(r)
 			continue
 		}
-		ch, err := strconv.Atoi(string(r))
-		if err != nil {
-			panic("strconv")
+		if lang == "" {
+			return nil, fmt.Errorf("invalid atag %q (a language is expected before the number of channels)", val)
 		}
+		ch := int(r - '0')
 		switch lang {
 		case "r":
 			lang = "rus"
@@ -341,6 +391,9 @@ func (o *TTagname) GetAudio() ([]TAudio, error) {
 		ret = append(ret, TAudio{lang, ch})
 		lang = ""
 	}
+	if lang != "" {
+		return nil, fmt.Errorf("invalid atag %q (the number of channels of %q is absent)", val, lang)
+	}
 	return ret, nil
 }
 
@@ -350,6 +403,9 @@ func (o *TTagname) GetSubtitle() ([]string, error) {
 	if err != nil {
 		return nil, nil
 	}
+	if len(val) < 2 || val[0] != 's' {
+		return nil, fmt.Errorf("invalid stag %q", val)
+	}
 	// fill a ret struct
 	ret := []string{}
 	lang := ""
@@ -386,6 +442,20 @@ type TFormat struct {
 	Quality    int
 	CacheType  int
 	Sbs        bool
+
+	// allowed values of the video stream by the check context, empty - any
+	FrameRates     []string // as ffprobe reports them, e.g. '25/1', '24000/1001'
+	FieldOrders    []string // 'progressive', 'tt', 'bb', 'tb', 'bt'
+	PixFmts        []string
+	BitDepth       int // 0 - any
+	ColorPrimaries []string
+	ColorTransfers []string
+	Hdr            bool // colour metadata must be present, otherwise unknown values are accepted
+}
+
+// Resolution -
+func (o *TFormat) Resolution() TResolution {
+	return o.resolution
 }
 
 func newFormat() *TFormat {
@@ -396,9 +466,9 @@ func newFormat() *TFormat {
 func (o *TTagname) Describe() (*TFormat, error) {
 	format := newFormat()
 	quality, err := o.GetQuality()
-	// if err != nil {
-	// 	return nil, err
-	// }
+	if _, e := o.GetTag("qtag"); e == nil && err != nil {
+		return nil, err
+	}
 
 	frm, err := o.GetFormat()
 	switch frm {
@@ -423,6 +493,13 @@ func (o *TTagname) Describe() (*TFormat, error) {
 			format.Sar = "64:45"
 		}
 	}
+	typ, _ := o.GetType()
+	getVideoCC(typ).Video[frm].applyTo(format)
+	if o.isPalMaster() {
+		// qtag describes an interlaced PAL master
+		format.FrameRates = []string{"25/1"}
+		format.FieldOrders = interlacedFieldOrders
+	}
 
 	format.Audio, err = o.GetAudio()
 	if err != nil {
@@ -1,6 +1,7 @@
 package tagname
 
 import (
+	"sync"
 	"testing"
 )
 
@@ -236,6 +237,28 @@ func TestTagnameCorrect(t *testing.T) {
 	}
 }
 
+// TestTagnameConcurrent - run with -race
+func TestTagnameConcurrent(t *testing.T) {
+	wg := sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, v := range tableTagnameCorrect {
+				tagname, err := NewFromFilename(v.input, false)
+				if err != nil {
+					t.Errorf("\n%q\nNewFormFromFile() error:\n%v", v.input, err)
+					continue
+				}
+				if _, err := tagname.ConvertTo(v.settings); err != nil {
+					t.Errorf("\n%q\nConvertTo() error: %v", v.input, err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
 // TestTagnameIncorrect -
 func TestTagnameIncorrect(t *testing.T) {
 	for _, v := range tableTagnameIncorrect {
@@ -22,6 +22,7 @@ const (
 // TTags -
 type TTags struct {
 	byType map[string][]string
+	spans  map[tTagKey]TSpan
 }
 
 var (
@@ -30,19 +31,21 @@ var (
 )
 
 func init() {
-	p, err := ptool.NewBuilder().FromString(oldForm).Entries("entry").Build()
+	p, err := buildParser(oldForm)
 	if err != nil {
 		fmt.Println("\n[old form] parser error: ", err)
 		panic("")
 	}
 	oldParser = p
-	p, err = ptool.NewBuilder().FromString(rtForm).Entries("entry").Build()
+	p, err = buildParser(rtForm)
 	if err != nil {
 		fmt.Println("\n[RT form] parser error: ", err)
 		panic("")
 	}
 	rtParser = p
 
+	oldNormalSchema.grammar = oldForm
+	rtNormalSchema.grammar = rtForm
 	globSchemas = map[string]*TSchema{}
 	RegisterSchema("old", oldNormalSchema)
 	RegisterSchema("rt", rtNormalSchema)
@@ -59,6 +62,8 @@ func NewTags(tree *ptool.TNode, parser *ptool.TParser, schema *TSchema) (*TTags,
 		typ := parser.ByID(node.Type)
 
 		tags.AddTag(typ, val)
+		start := node.Pos.Offset()
+		tags.addSpan(typ, val, TSpan{start, start + len(val)})
 	}
 	return tags, nil
 }
@@ -140,10 +145,12 @@ func Parse(s string, schemaName string) (*TTags, error) {
 		return nil, err
 	}
 
+	schema.mtx.Lock()
 	parser := *schema.parser
 	tree, err := parser.Parse(s)
+	schema.mtx.Unlock()
 	if err != nil {
-		return nil, err
+		return nil, newSchemaError(schema.name, s, err)
 	}
 
 	tags, err := NewTags(tree, parser, schema)
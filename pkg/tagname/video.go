@@ -0,0 +1,134 @@
+package tagname
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// tVideoRules - limits of the video stream of a format, zero values do not limit
+type tVideoRules struct {
+	FrameRates     []string // as ffprobe reports them, e.g. '25/1', '24000/1001'
+	FieldOrders    []string // 'progressive', 'tt', 'bb', 'tb', 'bt'
+	PixFmts        []string
+	BitDepth       int
+	ColorPrimaries []string
+	ColorTransfers []string
+	Hdr            string // 'yes' - colour metadata must be present, otherwise unknown values are accepted
+}
+
+var (
+	progressiveFrameRates = []string{"24000/1001", "24/1", "25/1", "30000/1001", "30/1", "50/1", "60000/1001", "60/1"}
+	interlacedFieldOrders = []string{"tt", "bb", "tb", "bt"}
+
+	// builtinVideoRules - progressive 8-bit bt709 hd, 4k as HDR10 or HLG, profiles override them
+	builtinVideoRules = map[string]tVideoRules{
+		"hd": {FrameRates: progressiveFrameRates, FieldOrders: []string{"progressive"}, PixFmts: []string{"yuv420p"},
+			BitDepth: 8, ColorPrimaries: []string{"bt709"}, ColorTransfers: []string{"bt709"}},
+		"3d": {FrameRates: progressiveFrameRates, FieldOrders: []string{"progressive"}, PixFmts: []string{"yuv420p"},
+			BitDepth: 8, ColorPrimaries: []string{"bt709"}, ColorTransfers: []string{"bt709"}},
+		"4k": {FrameRates: progressiveFrameRates, FieldOrders: []string{"progressive"}, PixFmts: []string{"yuv420p10le"},
+			BitDepth: 10, ColorPrimaries: []string{"bt2020"}, ColorTransfers: []string{"smpte2084", "arib-std-b67"}, Hdr: "yes"},
+		"sd": {PixFmts: []string{"yuv420p"}, BitDepth: 8},
+	}
+)
+
+// updateVideoRules - limits of in2 override the ones of in1 format by format
+func updateVideoRules(in1, in2 map[string]tVideoRules) map[string]tVideoRules {
+	if len(in1) == 0 && len(in2) == 0 {
+		return nil
+	}
+	ret := map[string]tVideoRules{}
+	for sdhd, rules := range in1 {
+		ret[sdhd] = rules
+	}
+	for sdhd, rules := range in2 {
+		r := ret[sdhd]
+		if len(rules.FrameRates) > 0 {
+			r.FrameRates = rules.FrameRates
+		}
+		if len(rules.FieldOrders) > 0 {
+			r.FieldOrders = rules.FieldOrders
+		}
+		if len(rules.PixFmts) > 0 {
+			r.PixFmts = rules.PixFmts
+		}
+		if rules.BitDepth > 0 {
+			r.BitDepth = rules.BitDepth
+		}
+		if len(rules.ColorPrimaries) > 0 {
+			r.ColorPrimaries = rules.ColorPrimaries
+		}
+		if len(rules.ColorTransfers) > 0 {
+			r.ColorTransfers = rules.ColorTransfers
+		}
+		if rules.Hdr != "" {
+			r.Hdr = rules.Hdr
+		}
+		ret[sdhd] = r
+	}
+	return ret
+}
+
+// isVideoFormat - the sdhd values the video limits are set for
+func isVideoFormat(s string) bool {
+	switch s {
+	case "sd", "hd", "3d", "4k":
+		return true
+	}
+	return false
+}
+
+// setVideoRule - sets a limit of the format by a check profile key
+func (o *tCheckContext) setVideoRule(sdhd, key, val string) error {
+	if !isVideoFormat(sdhd) {
+		return fmt.Errorf("unknown format %q (want 'sd', 'hd', '3d' or '4k')", sdhd)
+	}
+	if o.Video == nil {
+		o.Video = map[string]tVideoRules{}
+	}
+	r := o.Video[sdhd]
+	switch key {
+	default:
+		return fmt.Errorf("unknown key %q", key)
+	case "framerates":
+		for _, s := range strings.Fields(val) {
+			if !isKnownFrameRate(s) {
+				return fmt.Errorf("%v: invalid frame rate %q", key, s)
+			}
+		}
+		r.FrameRates = strings.Fields(val)
+	case "fieldorders":
+		r.FieldOrders = strings.Fields(val)
+	case "pixfmts":
+		r.PixFmts = strings.Fields(val)
+	case "bitdepth":
+		depth, err := strconv.Atoi(val)
+		if err != nil || depth <= 0 {
+			return fmt.Errorf("%v: invalid value %q", key, val)
+		}
+		r.BitDepth = depth
+	case "primaries":
+		r.ColorPrimaries = strings.Fields(val)
+	case "transfers":
+		r.ColorTransfers = strings.Fields(val)
+	case "hdr":
+		if val != "yes" && val != "no" {
+			return fmt.Errorf("%v: want 'yes' or 'no', have %q", key, val)
+		}
+		r.Hdr = val
+	}
+	o.Video[sdhd] = r
+	return nil
+}
+
+// applyTo - sets the limits of the video stream of the format
+func (o tVideoRules) applyTo(format *TFormat) {
+	format.FrameRates = o.FrameRates
+	format.FieldOrders = o.FieldOrders
+	format.PixFmts = o.PixFmts
+	format.BitDepth = o.BitDepth
+	format.ColorPrimaries = o.ColorPrimaries
+	format.ColorTransfers = o.ColorTransfers
+	format.Hdr = o.Hdr == "yes"
+}
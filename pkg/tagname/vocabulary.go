@@ -0,0 +1,158 @@
+package tagname
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Vocabulary file format:
+//
+//	// comment
+//	vtag           = redheadsound jaskier   // dubbing studios ('vredheadsound')
+//	EXCLUSIVE_TAGS = redheadsound premier   // exclusive tags ('mpremier'), a studio becomes a vtag
+//	agetag         = 03                     // age ratings
+//
+// Words are added to the built-in lists and spliced into the grammar as
+// alternations of literals where '${list}' is placed.
+
+// EnvVocabularyFile - a vocabulary file to load at startup
+const EnvVocabularyFile = "IMED_VOCABULARY"
+
+var vocabularyMtx sync.RWMutex
+
+var vocabularyLists = map[string][]string{
+	"vtag":           {"goblin", "kurazhbambey", "lostfilm", "newstudio", "pozitiv"},
+	"EXCLUSIVE_TAGS": {"amed", "abc", "pb", "vp", "disney", "oscar", "dk", "ru", "pryamoiz", "newstudio", "pozitiv", "lostfilm"},
+	"agetag":         {"00", "06", "12", "16", "18", "99"},
+}
+
+var (
+	reVocabularyWord = regexp.MustCompile(`^[a-zA-Z0-9]+$`)
+	reVocabularyList = regexp.MustCompile(`\$\{(\w+)\}`)
+)
+
+// VocabularyLists - names of the vocabulary lists
+func VocabularyLists() []string {
+	vocabularyMtx.RLock()
+	defer vocabularyMtx.RUnlock()
+	ret := make([]string, 0, len(vocabularyLists))
+	for key := range vocabularyLists {
+		ret = append(ret, key)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// VocabularyList - words of the list
+func VocabularyList(name string) []string {
+	vocabularyMtx.RLock()
+	defer vocabularyMtx.RUnlock()
+	return append([]string(nil), vocabularyLists[name]...)
+}
+
+func isVocabularyWord(list, word string) bool {
+	vocabularyMtx.RLock()
+	defer vocabularyMtx.RUnlock()
+	for _, s := range vocabularyLists[list] {
+		if s == word {
+			return true
+		}
+	}
+	return false
+}
+
+// spliceVocabulary - replaces '${list}' of the grammar with an alternation of the words of the list.
+// Longer words go first, so a word is not shadowed by its prefix.
+func spliceVocabulary(grammar string) string {
+	vocabularyMtx.RLock()
+	defer vocabularyMtx.RUnlock()
+	return reVocabularyList.ReplaceAllStringFunc(grammar, func(s string) string {
+		words, ok := vocabularyLists[reVocabularyList.FindStringSubmatch(s)[1]]
+		if !ok {
+			return s
+		}
+		words = append([]string(nil), words...)
+		sort.SliceStable(words, func(i, j int) bool {
+			return len(words[i]) > len(words[j])
+		})
+		return "'" + strings.Join(words, "'|'") + "'"
+	})
+}
+
+// AddVocabulary - adds the words to the list, the parsers are rebuilt by RebuildSchemas
+func AddVocabulary(list string, words ...string) error {
+	vocabularyMtx.Lock()
+	defer vocabularyMtx.Unlock()
+	if _, ok := vocabularyLists[list]; !ok {
+		return fmt.Errorf("unknown vocabulary list %q", list)
+	}
+	// nothing is added if any of the words is invalid
+	for _, word := range words {
+		if !reVocabularyWord.MatchString(word) {
+			return fmt.Errorf("%v: invalid word %q (want letters and digits only)", list, word)
+		}
+	}
+	for _, word := range words {
+		found := false
+		for _, s := range vocabularyLists[list] {
+			found = found || s == word
+		}
+		if !found {
+			vocabularyLists[list] = append(vocabularyLists[list], word)
+		}
+	}
+	return nil
+}
+
+// LoadVocabularyFile - adds the words of the vocabulary file and rebuilds the parsers of the registered schemas
+func LoadVocabularyFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Text()
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(line, "=")
+		if !ok {
+			return fmt.Errorf("%v:%v: want 'list = words', have %q", path, lineNo, line)
+		}
+		if err := AddVocabulary(strings.TrimSpace(key), strings.Fields(val)...); err != nil {
+			return fmt.Errorf("%v:%v: %v", path, lineNo, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return RebuildSchemas()
+}
+
+// RebuildSchemas - rebuilds the parsers of the registered schemas with the current vocabulary
+func RebuildSchemas() error {
+	for _, name := range Schemas() {
+		schema := globSchemas[name]
+		parser, err := buildParser(schema.grammar)
+		if err != nil {
+			return fmt.Errorf("schema %q: parser error: %v", name, err)
+		}
+		schema.mtx.Lock()
+		*schema.parser = parser
+		schema.mtx.Unlock()
+	}
+	return nil
+}
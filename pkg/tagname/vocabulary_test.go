@@ -0,0 +1,78 @@
+package tagname
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestLoadVocabularyFile -
+func TestLoadVocabularyFile(t *testing.T) {
+	saved := map[string][]string{}
+	for key, list := range vocabularyLists {
+		saved[key] = append([]string(nil), list...)
+	}
+	defer func() {
+		vocabularyLists = saved
+		if err := RebuildSchemas(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	if _, err := NewFromString("", "the_name_2018__hd_03.trailer.mp4", false); err == nil {
+		t.Fatalf("an unknown agetag is valid")
+	}
+
+	path := filepath.Join(t.TempDir(), "test.vocabulary")
+	data := "// test\nvtag = redhead\nEXCLUSIVE_TAGS = redhead premier\nagetag = 03\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadVocabularyFile(path); err != nil {
+		t.Fatalf("LoadVocabularyFile() error: %v", err)
+	}
+
+	table := []struct {
+		input, schema, check string
+	}{
+		{"the_name_2018__hd_vredhead.trailer.mp4", "rt", "hd_2018_the_name__ar2_vredhead_trailer.mp4"},
+		{"the_name_2018__hd_redhead_premier.trailer.mp4", "old", "the_name_2018__hd_ar2_mpremier_vredhead.trailer.mp4"},
+		{"the_name_2018__hd_03.trailer.mp4", "old", "the_name_2018__hd_03_ar2.trailer.mp4"},
+	}
+	for _, v := range table {
+		tn, err := NewFromString("", v.input, false)
+		if err != nil {
+			t.Errorf("\n%q\nNewFromString() error: %v", v.input, err)
+			continue
+		}
+		res, err := tn.ConvertTo(v.schema)
+		if err != nil || res != v.check {
+			t.Errorf("\n%q\nConvertTo(%q) = %q, %v, want %q", v.input, v.schema, res, err, v.check)
+		}
+	}
+}
+
+// TestVocabularyIncorrect -
+func TestVocabularyIncorrect(t *testing.T) {
+	table := []struct {
+		list string
+		word string
+	}{
+		{"unknown", "x"},
+		{"vtag", "a'|'b"},
+		{"agetag", ""},
+	}
+	for _, v := range table {
+		if err := AddVocabulary(v.list, v.word); err == nil {
+			t.Errorf("AddVocabulary(%q, %q) has no error", v.list, v.word)
+		}
+	}
+	// a line with an invalid word adds nothing
+	n := len(vocabularyLists["vtag"])
+	if err := AddVocabulary("vtag", "redhead", "a b"); err == nil {
+		t.Errorf("AddVocabulary() of an invalid word has no error")
+	}
+	if len(vocabularyLists["vtag"]) != n {
+		t.Errorf("AddVocabulary() with an invalid word added %v word(s)", len(vocabularyLists["vtag"])-n)
+	}
+}
@@ -0,0 +1,104 @@
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// tFileState - the last seen state of a file
+type tFileState struct {
+	size     int64
+	modTime  time.Time
+	since    time.Time // the time the file was seen with this size and mtime first
+	reported bool
+}
+
+// TWatcher - finds files in a directory tree that have stopped growing. It polls the tree
+// instead of using file system notifications, so it behaves the same on local disks and
+// network shares of any OS.
+type TWatcher struct {
+	root   string
+	settle time.Duration
+	skip   func(rel string, fi os.FileInfo) bool
+	files  map[string]*tFileState
+	now    func() time.Time
+}
+
+// New - creates a watcher of the root directory. A file is reported when its size
+// and mtime have not changed for the settle duration.
+func New(root string, settle time.Duration) *TWatcher {
+	return &TWatcher{
+		root:   root,
+		settle: settle,
+		files:  map[string]*tFileState{},
+		now:    time.Now,
+	}
+}
+
+// Skip - sets a function that excludes files and directories (rel is a path relative to the root).
+// Hidden files and directories (starting with '.') are always skipped.
+func (o *TWatcher) Skip(fn func(rel string, fi os.FileInfo) bool) {
+	o.skip = fn
+}
+
+// Root -
+func (o *TWatcher) Root() string {
+	return o.root
+}
+
+// Poll - scans the tree and returns sorted paths of the files that have settled since the
+// previous poll. A reported file is reported again only if it changes.
+func (o *TWatcher) Poll() ([]string, error) {
+	now := o.now()
+	seen := map[string]bool{}
+	err := filepath.Walk(o.root, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			if path == o.root {
+				return err
+			}
+			return nil // the file has been removed while walking
+		}
+		if path == o.root {
+			return nil
+		}
+		rel, err := filepath.Rel(o.root, path)
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(fi.Name(), ".") || (o.skip != nil && o.skip(rel, fi)) {
+			if fi.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !fi.Mode().IsRegular() {
+			return nil
+		}
+		seen[path] = true
+		state, ok := o.files[path]
+		if !ok || state.size != fi.Size() || !state.modTime.Equal(fi.ModTime()) {
+			o.files[path] = &tFileState{size: fi.Size(), modTime: fi.ModTime(), since: now}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	ret := []string{}
+	for path, state := range o.files {
+		if !seen[path] {
+			delete(o.files, path)
+			continue
+		}
+		if state.reported || now.Sub(state.since) < o.settle {
+			continue
+		}
+		state.reported = true
+		ret = append(ret, path)
+	}
+	sort.Strings(ret)
+	return ret, nil
+}
@@ -0,0 +1,71 @@
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPoll(t *testing.T) {
+	root := t.TempDir()
+	write := func(rel, data string) string {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	now := time.Now()
+	w := New(root, 10*time.Second)
+	w.now = func() time.Time { return now }
+	w.Skip(func(rel string, fi os.FileInfo) bool {
+		return fi.IsDir() && rel == "done"
+	})
+
+	a := write("a.mp4", "a")
+	b := write("sub/b.mp4", "b")
+	write("done/c.mp4", "c")
+	write(".part.mp4", "d")
+
+	type tStep struct {
+		after  time.Duration
+		action func()
+		want   []string
+	}
+	steps := []tStep{
+		{0, nil, []string{}},
+		{5 * time.Second, nil, []string{}},
+		{5 * time.Second, func() { write("sub/b.mp4", "bb") }, []string{a}},
+		{5 * time.Second, nil, []string{}},
+		{5 * time.Second, nil, []string{b}},
+		{time.Minute, nil, []string{}},
+		{time.Second, func() { os.Remove(a); write("a.mp4", "aaa") }, []string{}},
+		{10 * time.Second, nil, []string{a}},
+	}
+	for i, step := range steps {
+		now = now.Add(step.after)
+		if step.action != nil {
+			step.action()
+		}
+		got, err := w.Poll()
+		if err != nil {
+			t.Fatalf("#%v: unexpected error: %v", i, err)
+		}
+		if !reflect.DeepEqual(got, step.want) {
+			t.Errorf("#%v: got %v, want %v", i, got, step.want)
+		}
+	}
+}
+
+func TestPollNoRoot(t *testing.T) {
+	w := New(filepath.Join(t.TempDir(), "absent"), time.Second)
+	if _, err := w.Poll(); err == nil {
+		t.Errorf("error expected")
+	}
+}
@@ -3,6 +3,7 @@ package zlog
 import (
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/macroblock/imed/pkg/zlog/loglevel"
@@ -13,6 +14,7 @@ import (
 var defaultLog *TLog
 
 type tNode struct {
+	mtx     sync.Mutex
 	state   loglevel.TFilter
 	loggers []*zlogger.TLogger
 }
@@ -52,16 +54,22 @@ func (o *TLog) Instance(name string) *TLog {
 
 // SetState -
 func (o *TLog) SetState(level loglevel.TFilter) {
+	o.node.mtx.Lock()
+	defer o.node.mtx.Unlock()
 	o.node.state = level
 }
 
 // State -
 func (o *TLog) State() loglevel.TFilter {
+	o.node.mtx.Lock()
+	defer o.node.mtx.Unlock()
 	return o.node.state
 }
 
 // String -
 func (o *TLog) String() string {
+	o.node.mtx.Lock()
+	defer o.node.mtx.Unlock()
 	sl := []string{}
 	for _, l := range o.node.loggers {
 		sl = append(sl, l.LevelFilter().String()) //+": "+strings.Join(l.prefixes, ","))
@@ -72,6 +80,8 @@ func (o *TLog) String() string {
 // Add -
 func (o *TLog) Add(logger ...*zlogger.TLogger) {
 	// TODO: check on nil
+	o.node.mtx.Lock()
+	defer o.node.mtx.Unlock()
 	o.node.loggers = append(o.node.loggers, logger...)
 }
 
@@ -84,6 +94,8 @@ func (o *TLog) Log(level loglevel.TLevel, resetFilter loglevel.TFilter, err erro
 		text = err.Error()
 		err = nil
 	}
+	// messages of concurrent callers must not interleave
+	o.node.mtx.Lock()
 	formatParams := zlogger.TFormatParams{
 		Time:       time.Now(),
 		LogLevel:   level,
@@ -110,6 +122,7 @@ func (o *TLog) Log(level loglevel.TLevel, resetFilter loglevel.TFilter, err erro
 			fmt.Println(err)
 		}
 	}
+	o.node.mtx.Unlock()
 	if level == loglevel.Panic {
 		panic(text)
 	}